@@ -0,0 +1,235 @@
+package bpacc
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/accumulators-agg/go-poly/fft"
+	"github.com/alinush/go-mcl"
+)
+
+// Accumulator owns a set of distinct elements on top of a BpAcc, and computes its digest and proofs on demand.
+// The accumulator polynomial, its subproduct tree, the digest and the membership witnesses of all the elements are
+// cached until the next Add or Delete. The polynomial follows the updates with field operations only, and so does
+// the digest when the BpAcc holds the trapdoor (see DynAcc); the tree and the witnesses are rebuilt on demand.
+// Not safe for concurrent use.
+type Accumulator struct {
+	acc *BpAcc
+
+	elements []mcl.Fr
+	index    map[string]int // Serialized element -> position in elements
+
+	poly      []mcl.Fr // nil when stale
+	tree      *subproductNode
+	digest    mcl.G1
+	hasDigest bool
+	witnesses []mcl.G1 // Of elements, in the same order
+}
+
+// Takes ownership of a copy of elements. Panics on duplicates.
+func NewAccumulator(acc *BpAcc, elements []mcl.Fr) *Accumulator {
+	self := &Accumulator{acc: acc, index: make(map[string]int)}
+	self.Add(elements)
+	return self
+}
+
+func elementKey(y *mcl.Fr) string {
+	return string(y.Serialize())
+}
+
+func (self *Accumulator) Size() int {
+	return len(self.elements)
+}
+
+// The elements of the set, in no particular order. Must not be modified.
+func (self *Accumulator) Elements() []mcl.Fr {
+	return self.elements
+}
+
+func (self *Accumulator) Contains(y mcl.Fr) bool {
+	_, ok := self.index[elementKey(&y)]
+	return ok
+}
+
+// Adds the elements, which must be neither in the set nor repeated.
+func (self *Accumulator) Add(elements []mcl.Fr) {
+	if len(elements) == 0 {
+		return
+	}
+	if uint64(len(self.elements)+len(elements)) > self.acc.Q {
+		panic(fmt.Sprintf("Wants to accumulate %d, but the accumulator supports only %d", len(self.elements)+len(elements), self.acc.Q))
+	}
+	added := make(map[string]bool, len(elements))
+	for i := range elements {
+		key := elementKey(&elements[i])
+		if _, ok := self.index[key]; ok || added[key] {
+			panic(fmt.Sprintf("Element %s is already in the set.", elements[i].GetString(16)))
+		}
+		added[key] = true
+	}
+	for i := range elements {
+		self.index[elementKey(&elements[i])] = len(self.elements)
+		self.elements = append(self.elements, elements[i])
+	}
+
+	if self.poly != nil {
+		self.poly = fft.PolyMul(self.poly, polyTree(elements))
+	}
+	if self.hasDigest && self.acc.HasTrapdoor() {
+		p := self.acc.evalAtS(elements)
+		mcl.G1Mul(&self.digest, &self.digest, &p)
+	} else {
+		self.hasDigest = false
+	}
+	self.tree, self.witnesses = nil, nil
+}
+
+// Deletes the elements, which must be in the set.
+func (self *Accumulator) Delete(elements []mcl.Fr) {
+	if len(elements) == 0 {
+		return
+	}
+	deleted := make(map[string]bool, len(elements))
+	for i := range elements {
+		key := elementKey(&elements[i])
+		if _, ok := self.index[key]; !ok || deleted[key] {
+			panic(fmt.Sprintf("Element %s is not in the set.", elements[i].GetString(16)))
+		}
+		deleted[key] = true
+	}
+	for i := range elements {
+		key := elementKey(&elements[i])
+		pos := self.index[key]
+		// Moves the last element into the hole
+		last := len(self.elements) - 1
+		self.elements[pos] = self.elements[last]
+		self.index[elementKey(&self.elements[pos])] = pos
+		self.elements = self.elements[:last]
+		delete(self.index, key)
+	}
+
+	if self.poly != nil {
+		self.poly, _ = fft.PolyDiv(self.poly, polyTree(elements))
+	}
+	if self.hasDigest && self.acc.HasTrapdoor() {
+		p := self.acc.evalAtS(elements)
+		mcl.FrInv(&p, &p)
+		mcl.G1Mul(&self.digest, &self.digest, &p)
+	} else {
+		self.hasDigest = false
+	}
+	self.tree, self.witnesses = nil, nil
+}
+
+// The accumulator polynomial f(x), the product of (x - y) over the set. Must not be modified.
+func (self *Accumulator) Poly() []mcl.Fr {
+	if self.poly == nil {
+		if len(self.elements) == 0 {
+			self.poly = []mcl.Fr{frOne()}
+		} else {
+			self.poly = self.subproductTree().poly
+		}
+	}
+	return self.poly
+}
+
+func (self *Accumulator) subproductTree() *subproductNode {
+	if self.tree == nil {
+		self.tree = newSubproductTree(self.elements)
+	}
+	return self.tree
+}
+
+// Same as Commit on the elements of the set.
+func (self *Accumulator) Digest() mcl.G1 {
+	if !self.hasDigest {
+		poly := self.Poly()
+		mcl.G1MulVec(&self.digest, self.acc.pkRange(0, uint64(len(poly))), poly)
+		self.hasDigest = true
+	}
+	return self.digest
+}
+
+func (self *Accumulator) position(y *mcl.Fr) int {
+	pos, ok := self.index[elementKey(y)]
+	if !ok {
+		panic(fmt.Sprintf("Element %s is not in the set.", y.GetString(16)))
+	}
+	return pos
+}
+
+// Membership witness of y, which must be in the set: g^{f(s)/(s - y)}.
+// Computes a division by (x - y) and a multi-exp of size |set|, unless the witnesses are cached.
+func (self *Accumulator) ProveMember(y mcl.Fr) mcl.G1 {
+	pos := self.position(&y)
+	if self.witnesses != nil {
+		return self.witnesses[pos]
+	}
+	q, _ := fft.PolyDiv(self.Poly(), linearFactor(&y))
+	var witness mcl.G1
+	mcl.G1MulVec(&witness, self.acc.pkRange(0, uint64(len(q))), q)
+	return witness
+}
+
+// Membership witnesses of the elements of I, in the same order.
+// Large batches compute (and cache) the witnesses of the whole set with MemProveAll instead.
+func (self *Accumulator) ProveMembers(I []mcl.Fr) []mcl.G1 {
+	positions := make([]int, len(I))
+	for i := range I {
+		positions[i] = self.position(&I[i])
+	}
+	witnesses := make([]mcl.G1, len(I))
+	logSize := bits.Len(uint(len(self.elements)))
+	if self.witnesses == nil && len(I) <= logSize*logSize {
+		for i := range I {
+			witnesses[i] = self.ProveMember(I[i])
+		}
+		return witnesses
+	}
+
+	if self.witnesses == nil {
+		self.witnesses = make([]mcl.G1, len(self.elements))
+		self.acc.memProveAllTree(self.subproductTree(), self.witnesses)
+	}
+	for i := range I {
+		witnesses[i] = self.witnesses[positions[i]]
+	}
+	return witnesses
+}
+
+// Non-membership proof of y, which must not be in the set, the same as NonMemProve.
+// Alpha = 1/f(y) and Beta = g^{-Alpha q(s)}, where f(x) = q(x)(x - y) + f(y), thus without xGCD.
+func (self *Accumulator) ProveNonMember(y mcl.Fr) NonMemProof {
+	if self.Contains(y) {
+		panic(fmt.Sprintf("Element %s is in the set.", y.GetString(16)))
+	}
+	var pi NonMemProof
+	q, r := polyDivMod(self.Poly(), linearFactor(&y))
+	var negAlpha mcl.Fr
+	mcl.FrInv(&pi.Alpha, &r[0])
+	mcl.FrNeg(&negAlpha, &pi.Alpha)
+	mcl.G1MulVec(&pi.Beta, self.acc.pkRange(0, uint64(len(q))), q)
+	mcl.G1Mul(&pi.Beta, &pi.Beta, &negAlpha)
+	return pi
+}
+
+// Non-membership proofs of the elements of I, in the same order, with NonMemProveAll on the cached polynomial.
+func (self *Accumulator) ProveNonMembers(I []mcl.Fr) []NonMemProof {
+	for i := range I {
+		if self.Contains(I[i]) {
+			panic(fmt.Sprintf("Element %s is in the set.", I[i].GetString(16)))
+		}
+	}
+	if uint64(len(I)) > self.acc.Q {
+		panic(fmt.Sprintf("Wants to commit %d, but the accumulator supports only %d", len(I), self.acc.Q))
+	}
+	return self.acc.nonMemProveAllPoly(self.Poly(), I)
+}
+
+// x - y
+func linearFactor(y *mcl.Fr) []mcl.Fr {
+	p := make([]mcl.Fr, 2)
+	mcl.FrNeg(&p[0], y)
+	p[1].SetInt64(1)
+	return p
+}
@@ -0,0 +1,110 @@
+package bpacc
+
+import (
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestAccumulator(t *testing.T) {
+
+	var acc, public BpAcc
+	acc.KeyGenMem(8, 7, "xyz")
+	public = acc
+	public.S.Clear()
+	elements := PopulateRandom(120)
+
+	// With and without the trapdoor, the digest follows the updates
+	for _, bp := range []*BpAcc{&acc, &public} {
+		set := NewAccumulator(bp, elements[:50])
+		expected, _ := acc.Commit(elements[:50])
+		if digest := set.Digest(); !digest.IsEqual(&expected) || set.Size() != 50 {
+			t.Fatalf("Digest differs from Commit")
+		}
+
+		set.Add(elements[50:80])
+		set.Delete(elements[10:20])
+		remaining := append(append([]mcl.Fr{}, elements[:10]...), elements[20:80]...)
+		expected, _ = acc.Commit(remaining)
+		digest := set.Digest()
+		if !digest.IsEqual(&expected) || set.Size() != len(remaining) || len(set.Poly()) != len(remaining)+1 {
+			t.Fatalf("Digest after updates differs from Commit")
+		}
+		if set.Contains(elements[15]) || !set.Contains(elements[79]) || set.Contains(elements[100]) {
+			t.Errorf("Contains is wrong after updates")
+		}
+
+		witness := set.ProveMember(elements[5])
+		if !acc.MemVerifySingle(digest, elements[5], witness) {
+			t.Errorf("Membership witness did not verify")
+		}
+		pi := set.ProveNonMember(elements[15])
+		expectedPi := acc.NonMemProve(remaining, elements[15:16])[0]
+		if !pi.Alpha.IsEqual(&expectedPi.Alpha) || !pi.Beta.IsEqual(&expectedPi.Beta) {
+			t.Errorf("Non-membership proof differs from NonMemProve")
+		}
+
+		// A large batch fills the witness cache, which the next update drops
+		witnesses := set.ProveMembers(remaining)
+		for _, j := range []int{0, 30, len(remaining) - 1} {
+			if !acc.MemVerifySingle(digest, remaining[j], witnesses[j]) {
+				t.Errorf("Witness %d of the batch did not verify", j)
+			}
+		}
+		if cached := set.ProveMember(remaining[3]); !cached.IsEqual(&witnesses[3]) {
+			t.Errorf("Cached witness differs")
+		}
+		set.Add(elements[80:81])
+		digest = set.Digest()
+		if !acc.MemVerifySingle(digest, remaining[3], set.ProveMember(remaining[3])) {
+			t.Errorf("Witness after an addition did not verify")
+		}
+		if small := set.ProveMembers(elements[80:81]); !acc.MemVerifySingle(digest, elements[80], small[0]) {
+			t.Errorf("Witness of a small batch did not verify")
+		}
+
+		proofs := set.ProveNonMembers(elements[90:120])
+		for j := range proofs {
+			if !acc.NonMemVerifySingle(digest, elements[90+j], &proofs[j]) {
+				t.Errorf("Non-membership proof %d of the batch did not verify", j)
+			}
+		}
+	}
+
+	empty := NewAccumulator(&acc, nil)
+	digest := empty.Digest()
+	pi := empty.ProveNonMember(elements[0])
+	if !acc.NonMemVerifySingle(digest, elements[0], &pi) {
+		t.Errorf("Non-membership in the empty set did not verify")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Membership in the empty set did not panic")
+			}
+		}()
+		empty.ProveMembers(elements[:1])
+	}()
+
+	set := NewAccumulator(&acc, elements[:5])
+	for _, update := range []func(){
+		func() { set.Add(elements[4:6]) },
+		func() { set.Add([]mcl.Fr{elements[6], elements[6]}) },
+		func() { set.Delete(elements[5:6]) },
+		func() { set.Delete([]mcl.Fr{elements[0], elements[0]}) },
+		func() { set.ProveMember(elements[6]) },
+		func() { set.ProveNonMember(elements[0]) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Invalid update or proof did not panic")
+				}
+			}()
+			update()
+		}()
+	}
+	if set.Size() != 5 || set.Contains(elements[5]) || !set.Contains(elements[0]) {
+		t.Errorf("Rejected updates changed the set")
+	}
+}
@@ -0,0 +1,275 @@
+package bpacc
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/accumulators-agg/go-poly/fft"
+	"github.com/alinush/go-mcl"
+)
+
+// All the membership witnesses of a set at once, by descending its subproduct tree in the exponent.
+// For a node v of the tree, with P_v(x) the product of (x - y) over its leaves and f(x) that of the whole set, let
+// K_v[i] = g^{s^i f(s) / P_v(s)} for i < |v|.
+// At the root K_root = PK[0 .. n-1], and at the leaf of y, K_y[0] = g^{f(s)/(s-y)} is the witness of y.
+// A child c with sibling b gets K_c[i] = prod_t K_v[i+t]^{P_b[t]} for i < |c|, a middle product of K_v and P_b,
+// computed with FFTs over G1 for the large nodes. Thus every level of the tree costs O(n log n) group operations,
+// O(n log^2 n) in total, instead of the O(n^2) of MemProve(nil, elements).
+// (NOTE): This is a log n factor above O(n log n). The Feist-Khovratovich amortization reaches O(n log n) only
+// when the elements are the roots of unity of an FFT domain, while the elements here are arbitrary.
+
+// Nodes smaller than this use multi-exps instead of FFTs.
+const WITNESS_FFT_THRESHOLD = 64
+
+type subproductNode struct {
+	poly        []mcl.Fr // P_v, |v| + 1 coefficients
+	size        int
+	left, right *subproductNode
+}
+
+// Unlike fft.SubProductTree, any number of elements, but at least one.
+func newSubproductTree(elements []mcl.Fr) *subproductNode {
+	if len(elements) == 0 {
+		panic("The subproduct tree of an empty set.")
+	}
+	if len(elements) == 1 {
+		poly := make([]mcl.Fr, 2)
+		mcl.FrNeg(&poly[0], &elements[0])
+		poly[1].SetInt64(1)
+		return &subproductNode{poly: poly, size: 1}
+	}
+	mid := len(elements) / 2
+	left := newSubproductTree(elements[:mid])
+	right := newSubproductTree(elements[mid:])
+	return &subproductNode{poly: fft.PolyMul(left.poly, right.poly), size: len(elements), left: left, right: right}
+}
+
+// Takes the set X, whose digest is Commit(X)
+// Returns the membership witnesses of all the elements of X, in the same order
+// Computes O(|X|log^2|X|) exponentiations, spread over Options.Workers goroutines
+func (self *BpAcc) MemProveAll(X []mcl.Fr) []mcl.G1 {
+
+	if uint64(len(X)) > self.Q {
+		panic(fmt.Sprintf("Wants to commit %d, but the accumulator supports only %d", len(X), self.Q))
+	}
+	witnesses := make([]mcl.G1, len(X))
+	if len(X) == 0 {
+		return witnesses
+	}
+
+	self.memProveAllTree(newSubproductTree(X), witnesses)
+	return witnesses
+}
+
+func (self *BpAcc) memProveAllTree(tree *subproductNode, witnesses []mcl.G1) {
+	K := append([]mcl.G1{}, self.pkRange(0, uint64(tree.size))...)
+	descendWitnesses(tree, K, witnesses, self.workers())
+}
+
+// Writes the witnesses of the leaves of node into out, from K_node.
+func descendWitnesses(node *subproductNode, K []mcl.G1, out []mcl.G1, parallel int) {
+	if node.size == 1 {
+		out[0] = K[0]
+		return
+	}
+	left, right := node.left, node.right
+	if parallel < 2 {
+		descendWitnesses(left, middleProductG1(K, right.poly, left.size, 1), out[:left.size], 1)
+		descendWitnesses(right, middleProductG1(K, left.poly, right.size, 1), out[left.size:], 1)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		descendWitnesses(left, middleProductG1(K, right.poly, left.size, parallel/2), out[:left.size], parallel/2)
+	}()
+	rest := parallel - parallel/2
+	descendWitnesses(right, middleProductG1(K, left.poly, right.size, rest), out[left.size:], rest)
+	wg.Wait()
+}
+
+// Non-membership proofs of all the elements of I, by the same descent on the subproduct tree of I.
+// With f(x) the polynomial of X, the proof of y is alpha = 1/f(y) and Beta = g^{b(s)}, b(x) = -alpha (f(x) - f(y))/(x - y).
+// The remainders r_v = f mod P_v are carried down the tree, as in fft.PolyMultiEvaluate, and give f(y) at the leaves.
+// Along with them, K_v[i] = g^{s^i A_v(s)}, where (f(x) - f(y))/(x - y) = A_v(x) P_v(x)/(x - y) + (r_v(x) - r_v(y))/(x - y)
+// for every y of v. At the root A_root = f div P_root, and a child c with sibling b gets A_c = A_v P_b + (r_v div P_c).
+// At the leaf of y, K_y[0] = g^{(f(s) - f(y))/(s - y)}.
+// (NOTE): fft.PolyMultiEvaluate would give the same f(y), but it keeps the remainders to itself, while the
+// descent in G1 needs every r_v to form r_v div P_c, and it takes only power-of-two trees. Calling it as well
+// would repeat the remainder descent for values the descent here already has at the leaves.
+
+// Takes two disjoint sets as inputs: X and I
+// Returns the same proofs as NonMemProve, for all the elements of I
+// Computes O(|X|log|X| + |I|log^2|I|) exponentiations and field operations, instead of |I| xGCD and |I| multi-exps of size |X|
+func (self *BpAcc) NonMemProveAll(X []mcl.Fr, I []mcl.Fr) []NonMemProof {
+
+	if uint64(len(X)) > self.Q || uint64(len(I)) > self.Q {
+		panic(fmt.Sprintf("Wants to commit %d and %d, but the accumulator supports only %d", len(X), len(I), self.Q))
+	}
+	return self.nonMemProveAllPoly(polyTree(X), I)
+}
+
+// Same as NonMemProveAll, from the polynomial f of X.
+func (self *BpAcc) nonMemProveAllPoly(f []mcl.Fr, I []mcl.Fr) []NonMemProof {
+	proofs := make([]NonMemProof, len(I))
+	if len(I) == 0 {
+		return proofs
+	}
+
+	tree := newSubproductTree(I)
+	A, r := polyDivMod(f, tree.poly)
+	workers := self.workers()
+	K := middleProductG1(self.pkRange(0, uint64(len(I)+len(A)-1)), A, len(I), workers)
+	self.descendNonMem(tree, K, r, proofs, workers)
+	for i := range proofs {
+		if proofs[i].Alpha.IsZero() {
+			panic("X and I are not disjoint.")
+		}
+	}
+	return proofs
+}
+
+func (self *BpAcc) descendNonMem(node *subproductNode, K []mcl.G1, r []mcl.Fr, out []NonMemProof, parallel int) {
+	if node.size == 1 {
+		// r = f(y), zero for a member of X, thus Alpha = 0 (checked by the caller, outside of the goroutines)
+		var negAlpha mcl.Fr
+		mcl.FrInv(&out[0].Alpha, &r[0])
+		mcl.FrNeg(&negAlpha, &out[0].Alpha)
+		mcl.G1Mul(&out[0].Beta, &K[0], &negAlpha)
+		return
+	}
+
+	child := func(c *subproductNode, b *subproductNode, out []NonMemProof, parallel int) {
+		q, rc := polyDivMod(r, c.poly)
+		Kc := middleProductG1(K, b.poly, c.size, parallel)
+		Kq := middleProductG1(self.pkRange(0, uint64(c.size+len(q)-1)), q, c.size, parallel)
+		for i := range Kc {
+			mcl.G1Add(&Kc[i], &Kc[i], &Kq[i])
+		}
+		self.descendNonMem(c, Kc, rc, out, parallel)
+	}
+
+	left, right := node.left, node.right
+	if parallel < 2 {
+		child(left, right, out[:left.size], 1)
+		child(right, left, out[left.size:], 1)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		child(left, right, out[:left.size], parallel/2)
+	}()
+	child(right, left, out[left.size:], parallel-parallel/2)
+	wg.Wait()
+}
+
+// Same as fft.PolyDiv, but a divisor of higher degree gives a zero quotient instead of a panic.
+func polyDivMod(a []mcl.Fr, b []mcl.Fr) ([]mcl.Fr, []mcl.Fr) {
+	if len(a) < len(b) {
+		return make([]mcl.Fr, 1), a
+	}
+	return fft.PolyDiv(a, b)
+}
+
+// Computes out[i] = prod_t K[i+t]^{P[t]} for i < size, where len(K) >= size + len(P) - 1.
+func middleProductG1(K []mcl.G1, P []mcl.Fr, size int, parallel int) []mcl.G1 {
+	out := make([]mcl.G1, size)
+	d := len(P) - 1
+	// Few outputs or a short P: the multi-exps cost less than the FFTs
+	if len(K) <= WITNESS_FFT_THRESHOLD || minInt(size, d+1) <= bits.Len(uint(len(K))) {
+		for i := range out {
+			mcl.G1MulVec(&out[i], K[i:i+d+1], P)
+		}
+		return out
+	}
+
+	// out[i] is the coefficient i+d of reverse(P) * K. The cyclic convolution of length N >= len(K)
+	// only wraps around onto the coefficients below d, thus it is enough.
+	N := uint64(1) << uint(bits.Len(uint(len(K)-1)))
+	fs := fft.NewFFTSettings(uint8(bits.Len64(N) - 1))
+
+	R := make([]mcl.Fr, N)
+	for t := 0; t <= d; t++ {
+		R[d-t] = P[t]
+	}
+	evalsR, err := fs.FFT(R, false)
+	check(err)
+	// Folds the 1/N of the inverse FFT in
+	var invN mcl.Fr
+	invN.SetInt64(int64(N))
+	mcl.FrInv(&invN, &invN)
+	for i := range evalsR {
+		mcl.FrMul(&evalsR[i], &evalsR[i], &invN)
+	}
+
+	vals := make([]mcl.G1, N)
+	copy(vals, K)
+	evalsK := fftG1(vals, fs.ExpandedRootsOfUnity[:N], parallel)
+	mulVecG1(evalsK, evalsR, parallel)
+	conv := fftG1(evalsK, fs.ReverseRootsOfUnity[:N], parallel)
+
+	copy(out, conv[d:d+size])
+	return out
+}
+
+// Radix-2 FFT over G1: out[k] = sum_j vals[j]^{roots[1]^{jk}}, where roots[i] = roots[1]^i and len(roots) = len(vals).
+// Passing the inverse roots gives N times the inverse FFT.
+func fftG1(vals []mcl.G1, roots []mcl.Fr, parallel int) []mcl.G1 {
+	out := make([]mcl.G1, len(vals))
+	fftG1Strided(vals, 1, roots, 1, out, parallel)
+	return out
+}
+
+func fftG1Strided(vals []mcl.G1, valsStride int, roots []mcl.Fr, rootsStride int, out []mcl.G1, parallel int) {
+	n := len(out)
+	if n == 1 {
+		out[0] = vals[0]
+		return
+	}
+	half := n / 2
+	if parallel < 2 {
+		fftG1Strided(vals, 2*valsStride, roots, 2*rootsStride, out[:half], 1)
+		fftG1Strided(vals[valsStride:], 2*valsStride, roots, 2*rootsStride, out[half:], 1)
+	} else {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fftG1Strided(vals, 2*valsStride, roots, 2*rootsStride, out[:half], parallel/2)
+		}()
+		fftG1Strided(vals[valsStride:], 2*valsStride, roots, 2*rootsStride, out[half:], parallel-parallel/2)
+		wg.Wait()
+	}
+
+	var odd mcl.G1
+	for k := 0; k < half; k++ {
+		mcl.G1Mul(&odd, &out[half+k], &roots[k*rootsStride])
+		mcl.G1Sub(&out[half+k], &out[k], &odd)
+		mcl.G1Add(&out[k], &out[k], &odd)
+	}
+}
+
+// points[i] = points[i]^{scalars[i]}
+func mulVecG1(points []mcl.G1, scalars []mcl.Fr, parallel int) {
+	if parallel < 1 {
+		parallel = 1
+	}
+	step := (len(points) + parallel - 1) / parallel
+	var wg sync.WaitGroup
+	for start := 0; start < len(points); start += step {
+		stop := minInt(start+step, len(points))
+		wg.Add(1)
+		go func(start int, stop int) {
+			defer wg.Done()
+			for i := start; i < stop; i++ {
+				mcl.G1Mul(&points[i], &points[i], &scalars[i])
+			}
+		}(start, stop)
+	}
+	wg.Wait()
+}
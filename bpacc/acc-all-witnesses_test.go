@@ -0,0 +1,92 @@
+package bpacc
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestMemProveAll(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 8, "xyz")
+	elements := PopulateRandom(200)
+
+	// Multi-exps only, FFTs at the top levels only, and more workers than leaves
+	for _, n := range []int{1, 2, 7, 65, 200} {
+		X := elements[:n]
+		digest, _ := acc.Commit(X)
+		witnesses := acc.MemProveAll(X)
+		for j := range X {
+			if !acc.MemVerifySingle(digest, X[j], witnesses[j]) {
+				t.Fatalf("%d elements: witness %d did not verify", n, j)
+			}
+		}
+		for _, j := range []int{0, n / 2, n - 1} {
+			rest := append(append([]mcl.Fr{}, X[:j]...), X[j+1:]...)
+			expected := acc.MemProve(rest, X[j:j+1])[0]
+			if !witnesses[j].IsEqual(&expected) {
+				t.Errorf("%d elements: witness %d differs from MemProve", n, j)
+			}
+		}
+	}
+
+	// Large enough for the FFT branch: more than WITNESS_FFT_THRESHOLD points, and both the outputs and P longer than log2 of that
+	size, d := 61, 19
+	points := make([]mcl.G1, size+d)
+	var g mcl.G1
+	g.Random()
+	for i := range points {
+		mcl.G1Dbl(&g, &g)
+		points[i] = g
+	}
+	M := make([]mcl.Fr, d+1)
+	for i := range M {
+		M[i].Random()
+	}
+	if len(points) <= WITNESS_FFT_THRESHOLD || minInt(size, d+1) <= bits.Len(uint(len(points))) {
+		t.Fatalf("Sizes take the multi-exp branch")
+	}
+	slow := make([]mcl.G1, size)
+	for i := range slow {
+		mcl.G1MulVec(&slow[i], points[i:i+d+1], M)
+	}
+	fast := middleProductG1(points, M, size, 3)
+	for i := range slow {
+		if !fast[i].IsEqual(&slow[i]) {
+			t.Errorf("Middle product %d differs from the multi-exp", i)
+		}
+	}
+}
+
+func TestNonMemProveAll(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 8, "xyz")
+	elements := PopulateRandom(200)
+
+	// More elements than X, fewer, and X empty
+	for _, sizes := range [][2]int{{3, 100}, {150, 1}, {120, 70}, {0, 5}} {
+		X, I := elements[:sizes[0]], elements[sizes[0]:sizes[0]+sizes[1]]
+		digest, _ := acc.Commit(X)
+		proofs := acc.NonMemProveAll(X, I)
+		for j := range I {
+			if !acc.NonMemVerifySingle(digest, I[j], &proofs[j]) {
+				t.Fatalf("%d and %d elements: proof %d did not verify", sizes[0], sizes[1], j)
+			}
+		}
+		expected := acc.NonMemProve(X, I[:1])[0]
+		if !proofs[0].Alpha.IsEqual(&expected.Alpha) || !proofs[0].Beta.IsEqual(&expected.Beta) {
+			t.Errorf("%d and %d elements: proof differs from NonMemProve", sizes[0], sizes[1])
+		}
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Proving the non-membership of a member did not panic")
+			}
+		}()
+		acc.NonMemProveAll(elements[:10], elements[5:15])
+	}()
+}
@@ -0,0 +1,489 @@
+package bpacc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alinush/go-mcl"
+	"golang.org/x/crypto/blake2b"
+)
+
+const CONTRIBUTION_NAME = "/contrib-%03d.data"
+
+// Multi-party setup of the SRS.
+// CeremonyInit writes parameters with s = alpha = 1, which anybody can check.
+// Every participant then loads the folder with KeyGenLoadPublic and runs Contribute,
+// which raises the i-th power by t^i (and the KEA powers by a) for fresh secrets t and a.
+// The final trapdoor is the product of all the t's, thus it is unknown as long as one participant is honest.
+// A participant who keeps t and a (ContributeExtendable) can raise the powers that TryExtend appends to the folder
+// later on with ExtendContribution; the extended setup is consistent once every participant has done so.
+
+// One step of the ceremony. Published next to the parameters as contrib-%03d.data
+type Contribution struct {
+	Index uint64
+	ELL   uint64 // The powers up to 2^ELL are raised by t, see ExtendContribution
+
+	PrevPK1     mcl.G1 // PK[1] before the contribution
+	PrevPKAlpha mcl.G1 // PKAlpha[0] before the contribution
+	PK1         mcl.G1 // PK[1] after the contribution
+	PKAlpha     mcl.G1 // PKAlpha[0] after the contribution
+
+	TauG1 mcl.G1 // g^t
+	TauG2 mcl.G2 // h^t
+	TauR  mcl.G1 // Schnorr commitment for t
+	TauZ  mcl.Fr // Schnorr response for t
+
+	AlphaG1 mcl.G1 // g^a
+	AlphaG2 mcl.G2 // h^a
+	AlphaR  mcl.G1 // Schnorr commitment for a
+	AlphaZ  mcl.Fr // Schnorr response for a
+}
+
+// Writes the starting point of the ceremony. No secret is involved.
+func (self *BpAcc) CeremonyInit(ncores uint8, L uint64, folderPath string) {
+	self.setWorkers(ncores)
+	self.Init(L, "", folderPath)
+	self.S.SetInt64(1)
+	self.Alpha.SetInt64(1)
+	self.GeneratorsGen()
+	self.SavePublic()
+	self.PrkVrkGen()
+	self.SaveManifest()
+	self.ClearTrapdoors()
+}
+
+// Adds fresh randomness to the parameters loaded with KeyGenLoadPublic, at the ELL of the folder.
+// Rewrites the segment files and the public file, publishes the contribution record and updates the manifest.
+// Fails for the mapped storage.
+func (self *BpAcc) Contribute() (Contribution, error) {
+	return self.contribute("")
+}
+
+// Same as Contribute, but t and a are also written to secretPath (outside the folder), so that the participant
+// can take part in a later extension of the setup with ExtendContribution.
+// (NOTE): The contribution only counts towards the secrecy of the trapdoor once that file is destroyed.
+func (self *BpAcc) ContributeExtendable(secretPath string) (Contribution, error) {
+	return self.contribute(secretPath)
+}
+
+func (self *BpAcc) contribute(secretPath string) (Contribution, error) {
+	if err := self.needsInMemory("Contribute"); err != nil {
+		return Contribution{}, err
+	}
+	if err := self.needsWholeFolder("Contribute"); err != nil {
+		return Contribution{}, err
+	}
+
+	var t, a mcl.Fr
+	self.randomFr(&t)
+	self.randomFr(&a)
+	for t.IsZero() || a.IsZero() {
+		self.randomFr(&t)
+		self.randomFr(&a)
+	}
+
+	var c Contribution
+	files, err := self.contributionFiles()
+	if err != nil {
+		return Contribution{}, err
+	}
+	c.Index = uint64(len(files))
+	c.ELL = self.ELL
+	if c.PrevPK1, err = self.PKAt(1); err != nil {
+		return Contribution{}, err
+	}
+	c.PrevPKAlpha = self.PKAlpha[0]
+
+	mcl.G1Mul(&c.TauG1, &self.G, &t)
+	mcl.G2Mul(&c.TauG2, &self.H, &t)
+	mcl.G1Mul(&c.AlphaG1, &self.G, &a)
+	mcl.G2Mul(&c.AlphaG2, &self.H, &a)
+
+	self.Rescale(t, a)
+	c.PK1 = self.PK[1]
+	c.PKAlpha = self.PKAlpha[0]
+
+	c.TauR, c.TauZ = self.schnorrProve(&c, t, "tau")
+	c.AlphaR, c.AlphaZ = self.schnorrProve(&c, a, "alpha")
+	if secretPath != "" {
+		err = self.saveContributionSecret(secretPath, c.Index, t, a)
+	}
+	t.Clear()
+	a.Clear()
+	if err != nil {
+		return c, err
+	}
+
+	if err := self.rewriteSegments(0); err != nil {
+		return c, err
+	}
+	if err := self.savePublic(); err != nil {
+		return c, err
+	}
+	if err := self.saveContribution(c); err != nil {
+		return c, err
+	}
+	return c, self.saveManifest()
+}
+
+// Raises the i-th power of PK, VK and PedVK by t^i, and the KEA powers additionally by a.
+// Panics for the mapped storage.
+func (self *BpAcc) Rescale(t mcl.Fr, a mcl.Fr) {
+	check(self.needsInMemory("Rescale"))
+	self.rescale(t, a, 0)
+	mcl.G1Mul(&self.PKAlpha[0], &self.PKAlpha[0], &a)
+}
+
+// Same as Rescale, for the powers from first on, and without PKAlpha.
+func (self *BpAcc) rescale(t mcl.Fr, a mcl.Fr, first uint64) {
+	var wg sync.WaitGroup
+
+	num := self.Q + 1
+	step := uint64(math.Ceil(float64(num-first) / float64(self.workers())))
+
+	for start := first; start < num; start += step {
+		stop := minUint64(start+step, num)
+		wg.Add(1)
+		go func(start uint64, stop uint64) {
+			defer wg.Done()
+			var ta mcl.Fr
+			x := FrPow(t, int64(start))
+			for i := start; i < stop; i++ {
+				mcl.FrMul(&ta, &x, &a)
+				mcl.G1Mul(&self.PK[i], &self.PK[i], &x)
+				mcl.G2Mul(&self.VK[i], &self.VK[i], &x)
+				mcl.G2Mul(&self.VKAlpha[i], &self.VKAlpha[i], &ta)
+				mcl.G2Mul(&self.PedVK[i], &self.PedVK[i], &x)
+				mcl.G2Mul(&self.PedVKAlpha[i], &self.PedVKAlpha[i], &ta)
+				mcl.FrMul(&x, &x, &t)
+			}
+			ta.Clear()
+			x.Clear()
+		}(start, stop)
+	}
+	wg.Wait()
+}
+
+// Raises the powers that TryExtend appended since the contribution of secretPath (written by ContributeExtendable)
+// by its t and a, as Contribute would have done at the larger ELL. The parameters are loaded with KeyGenLoadPublic
+// at the ELL of the folder. Rewrites the new segment files and the contribution record (whose ELL tells the powers
+// it covers, see VerifyCeremony) and updates the manifest.
+func (self *BpAcc) ExtendContribution(secretPath string) error {
+	if err := self.needsInMemory("ExtendContribution"); err != nil {
+		return err
+	}
+	if err := self.needsWholeFolder("ExtendContribution"); err != nil {
+		return err
+	}
+
+	var t, a mcl.Fr
+	defer t.Clear()
+	defer a.Clear()
+	index, err := self.loadContributionSecret(secretPath, &t, &a)
+	if err != nil {
+		return err
+	}
+	contributions, err := self.TryLoadContributions()
+	if err != nil {
+		return err
+	}
+	if index >= uint64(len(contributions)) {
+		return &FileError{secretPath, fmt.Errorf("%w: no contribution %d in the folder", ErrFormat, index)}
+	}
+	c := contributions[index]
+	var tauG1, alphaG1 mcl.G1
+	mcl.G1Mul(&tauG1, &self.G, &t)
+	mcl.G1Mul(&alphaG1, &self.G, &a)
+	if !tauG1.IsEqual(&c.TauG1) || !alphaG1.IsEqual(&c.AlphaG1) {
+		return &FileError{secretPath, errors.New("Contribution secret does not match its contribution record.")}
+	}
+	if c.ELL >= self.ELL {
+		return &FileError{secretPath, fmt.Errorf("%w: contribution %d already covers ELL %d", ErrEllMismatch, index, self.ELL)}
+	}
+
+	first := uint64(1)<<c.ELL + 1
+	self.rescale(t, a, first)
+	c.ELL = self.ELL
+	if err := self.rewriteSegments(first); err != nil {
+		return err
+	}
+	if err := self.saveContribution(c); err != nil {
+		return err
+	}
+	return self.saveManifest()
+}
+
+// Checks the chain of contributions against the loaded parameters.
+// The chain has to start from CeremonyInit and end at the current PK[1] and PKAlpha[0],
+// and the powers themselves have to pass VerifyParams.
+func (self *BpAcc) VerifyCeremony(contributions []Contribution) bool {
+
+	if len(contributions) == 0 {
+		self.logln("Ceremony: no contributions.")
+		return false
+	}
+
+	prevPK1 := self.G
+	prevPKAlpha := self.G
+	for k := range contributions {
+		c := &contributions[k]
+
+		if c.Index != uint64(k) {
+			self.logln("Ceremony: unexpected index", c.Index, "at", k)
+			return false
+		}
+		if c.ELL < self.ELL {
+			self.logln("Ceremony: contribution", k, "only covers ELL", c.ELL, "and has to run ExtendContribution.")
+			return false
+		}
+		if !c.PrevPK1.IsEqual(&prevPK1) || !c.PrevPKAlpha.IsEqual(&prevPKAlpha) {
+			self.logln("Ceremony: contribution", k, "does not build on the previous one.")
+			return false
+		}
+		if !self.VerifyContribution(c) {
+			self.logln("Ceremony: contribution", k, "is invalid.")
+			return false
+		}
+		prevPK1 = c.PK1
+		prevPKAlpha = c.PKAlpha
+	}
+
+	pk1, err := self.PKAt(1)
+	if err != nil {
+		self.logln("Ceremony:", err)
+		return false
+	}
+	if !prevPK1.IsEqual(&pk1) || !prevPKAlpha.IsEqual(&self.PKAlpha[0]) {
+		self.logln("Ceremony: parameters do not match the last contribution.")
+		return false
+	}
+	if err := self.VerifyParams(); err != nil {
+		self.logln("Ceremony:", err)
+		return false
+	}
+	return true
+}
+
+// Checks a single contribution on its own.
+func (self *BpAcc) VerifyContribution(c *Contribution) bool {
+
+	if c.TauG1.IsZero() || c.AlphaG1.IsZero() {
+		return false
+	}
+
+	// Proof of knowledge of t and a
+	if !self.schnorrVerify(c, c.TauG1, c.TauR, c.TauZ, "tau") {
+		return false
+	}
+	if !self.schnorrVerify(c, c.AlphaG1, c.AlphaR, c.AlphaZ, "alpha") {
+		return false
+	}
+
+	// g^t and h^t carry the same exponent
+	status := MultiPairing2(c.TauG1, self.H, self.G, c.TauG2)
+	status = status && MultiPairing2(c.AlphaG1, self.H, self.G, c.AlphaG2)
+
+	// The new values are the old ones raised by t and a
+	status = status && MultiPairing2(c.PK1, self.H, c.PrevPK1, c.TauG2)
+	status = status && MultiPairing2(c.PKAlpha, self.H, c.PrevPKAlpha, c.AlphaG2)
+	return status
+}
+
+func (self *BpAcc) schnorrProve(c *Contribution, x mcl.Fr, label string) (mcl.G1, mcl.Fr) {
+	var r, e, z mcl.Fr
+	var R mcl.G1
+	self.randomFr(&r)
+	mcl.G1Mul(&R, &self.G, &r)
+
+	e.SetHashOf(c.FiatShamir(R, label))
+	mcl.FrMul(&z, &e, &x)
+	mcl.FrAdd(&z, &z, &r)
+	r.Clear()
+	return R, z
+}
+
+// Checks g^z = R * X^e
+func (self *BpAcc) schnorrVerify(c *Contribution, X mcl.G1, R mcl.G1, z mcl.Fr, label string) bool {
+	var e mcl.Fr
+	e.SetHashOf(c.FiatShamir(R, label))
+
+	var lhs, rhs mcl.G1
+	mcl.G1Mul(&lhs, &self.G, &z)
+	mcl.G1Mul(&rhs, &X, &e)
+	mcl.G1Add(&rhs, &rhs, &R)
+	return lhs.IsEqual(&rhs)
+}
+
+// The challenge binds the proof to the position in the chain and to the public values.
+func (self *Contribution) FiatShamir(R mcl.G1, label string) []byte {
+	index := make([]byte, 8)
+	binary.LittleEndian.PutUint64(index, self.Index)
+	data := make([]byte, 0)
+	data = append(data, []byte(label)...)
+	data = append(data, index...)
+	data = append(data, self.PrevPK1.Serialize()...)
+	data = append(data, self.PrevPKAlpha.Serialize()...)
+	data = append(data, self.PK1.Serialize()...)
+	data = append(data, self.PKAlpha.Serialize()...)
+	data = append(data, self.TauG1.Serialize()...)
+	data = append(data, self.TauG2.Serialize()...)
+	data = append(data, self.AlphaG1.Serialize()...)
+	data = append(data, self.AlphaG2.Serialize()...)
+	data = append(data, R.Serialize()...)
+	hash := blake2b.Sum256(data)
+	return hash[:]
+}
+
+func (self *BpAcc) contributionFiles() ([]string, error) {
+	files, err := filepath.Glob(self.folderPath + "/contrib-[0-9][0-9][0-9].data")
+	if err != nil {
+		return nil, &FileError{self.folderPath, err}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Writes the contribution record c to contrib-%03d.data, after a FileHeader whose segment is c.Index.
+// The points are always compressed.
+func (self *BpAcc) SaveContribution(c Contribution) {
+	check(self.saveContribution(c))
+}
+
+func (self *BpAcc) saveContribution(c Contribution) error {
+
+	fileName := self.folderPath + fmt.Sprintf(CONTRIBUTION_NAME, c.Index)
+	self.logln("Saving data to:", fileName)
+
+	f, err := createFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := NewFileHeader(CONTRIBUTION_COMPONENT, c.ELL, uint32(c.Index), 0, 0)
+	if err := WriteHeader(f, header); err != nil {
+		return &FileError{fileName, err}
+	}
+	w := pointWriter{w: f, path: fileName, encoding: POINT_COMPRESSED}
+	for _, g := range []*mcl.G1{&c.PrevPK1, &c.PrevPKAlpha, &c.PK1, &c.PKAlpha, &c.TauG1, &c.TauR, &c.AlphaG1, &c.AlphaR} {
+		w.G1(g)
+	}
+	for _, h := range []*mcl.G2{&c.TauG2, &c.AlphaG2} {
+		w.G2(h)
+	}
+	for _, z := range []*mcl.Fr{&c.TauZ, &c.AlphaZ} {
+		w.Write(z.Serialize())
+	}
+	return w.Err()
+}
+
+// Reads all the contribution records of the folder, in order.
+func (self *BpAcc) LoadContributions() []Contribution {
+	contributions, err := self.TryLoadContributions()
+	check(err)
+	return contributions
+}
+
+func (self *BpAcc) TryLoadContributions() ([]Contribution, error) {
+
+	files, err := self.contributionFiles()
+	if err != nil {
+		return nil, err
+	}
+	contributions := make([]Contribution, len(files))
+	for k, fileName := range files {
+		if err := self.loadContribution(fileName, &contributions[k]); err != nil {
+			return nil, err
+		}
+	}
+	return contributions, nil
+}
+
+func (self *BpAcc) loadContribution(fileName string, c *Contribution) error {
+
+	// contrib-007.data -> 7
+	index, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(fileName), "contrib-"), ".data"), 10, 32)
+	if err != nil {
+		return &FileError{fileName, fmt.Errorf("%w: %v", ErrFormat, err)}
+	}
+
+	f, err := openFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header, err := ReadHeader(f)
+	if err == nil {
+		err = header.Expect(CONTRIBUTION_COMPONENT, uint32(index))
+	}
+	if err != nil {
+		return &FileError{fileName, err}
+	}
+	c.Index = uint64(header.Segment)
+	c.ELL = header.ELL
+
+	r := pointReader{r: f, path: fileName, encoding: self.reading(PointEncoding(header.Encoding))}
+	for _, g := range []*mcl.G1{&c.PrevPK1, &c.PrevPKAlpha, &c.PK1, &c.PKAlpha, &c.TauG1, &c.TauR, &c.AlphaG1, &c.AlphaR} {
+		r.G1(g)
+	}
+	for _, h := range []*mcl.G2{&c.TauG2, &c.AlphaG2} {
+		r.G2(h)
+	}
+	for _, z := range []*mcl.Fr{&c.TauZ, &c.AlphaZ} {
+		r.Fr(z)
+	}
+	return r.Err()
+}
+
+// Writes t and a of the index-th contribution to path, see ContributeExtendable.
+func (self *BpAcc) saveContributionSecret(path string, index uint64, t mcl.Fr, a mcl.Fr) error {
+
+	self.logln("Saving contribution secret to:", path)
+
+	os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return &FileError{path, err}
+	}
+	defer f.Close()
+
+	header := NewFileHeader(CONTRIBUTION_SECRET_COMPONENT, self.ELL, uint32(index), 0, 0)
+	if err := WriteHeader(f, header); err != nil {
+		return &FileError{path, err}
+	}
+	w := pointWriter{w: f, path: path}
+	w.Write(t.Serialize())
+	w.Write(a.Serialize())
+	return w.Err()
+}
+
+// Reads t and a written by saveContributionSecret, and returns the index of their contribution.
+func (self *BpAcc) loadContributionSecret(path string, t *mcl.Fr, a *mcl.Fr) (uint64, error) {
+
+	f, err := openFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	header, err := ReadHeader(f)
+	if err == nil {
+		err = header.Expect(CONTRIBUTION_SECRET_COMPONENT, header.Segment)
+	}
+	if err != nil {
+		return 0, &FileError{path, err}
+	}
+	r := pointReader{r: f, path: path}
+	r.Fr(t)
+	r.Fr(a)
+	return uint64(header.Segment), r.Err()
+}
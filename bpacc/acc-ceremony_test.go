@@ -0,0 +1,101 @@
+package bpacc
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCeremony(t *testing.T) {
+
+	l := uint64(4)
+	folder := t.TempDir()
+
+	var coordinator BpAcc
+	coordinator.CeremonyInit(8, l, folder)
+
+	for k := 0; k < 3; k++ {
+		var participant BpAcc
+		participant.KeyGenLoadPublic(8, l, folder)
+		if _, err := participant.Contribute(); err != nil {
+			t.Fatalf("Contribution %d failed: %s", k, err)
+		}
+	}
+
+	var acc BpAcc
+	acc.KeyGenLoadPublic(8, l, folder)
+	contributions := acc.LoadContributions()
+	if len(contributions) != 3 {
+		t.Fatalf("Expected 3 contributions, found %d", len(contributions))
+	}
+	if !acc.VerifyCeremony(contributions) {
+		t.Errorf("Ceremony did not verify.")
+	}
+
+	n := uint64(1 << 3)
+	elements := PopulateRandom(n)
+	digest, _ := acc.Commit(elements)
+	X, I := elements[:n/2], elements[n/2:]
+	proofs := acc.MemProve(X, I)
+	for k := range I {
+		if !acc.MemVerifySingle(digest, I[k], proofs[k]) {
+			t.Errorf("Proof did not verify %d", k)
+			break
+		}
+	}
+
+	if acc.VerifyCeremony(contributions[1:]) {
+		t.Errorf("Ceremony verified without its first contribution.")
+	}
+
+	contributions[1].TauZ.Random()
+	if acc.VerifyCeremony(contributions) {
+		t.Errorf("Ceremony verified with a forged proof of knowledge.")
+	}
+}
+
+// The contribution records carry a header and are covered by the manifest.
+func TestContributionFiles(t *testing.T) {
+
+	l := uint64(3)
+	folder := t.TempDir()
+	var coordinator BpAcc
+	coordinator.CeremonyInit(8, l, folder)
+
+	var participant BpAcc
+	participant.KeyGenLoadPublic(8, l, folder)
+	c, err := participant.Contribute()
+	if err != nil {
+		t.Fatalf("Contribution failed: %s", err)
+	}
+
+	var acc BpAcc
+	acc.KeyGenLoadPublic(8, l, folder)
+	contributions, err := acc.TryLoadContributions()
+	if err != nil || len(contributions) != 1 || !contributions[0].PK1.IsEqual(&c.PK1) || !contributions[0].TauZ.IsEqual(&c.TauZ) {
+		t.Fatalf("Contribution did not load back: %v", err)
+	}
+	header, err := readFileHeader(folder + "/contrib-000.data")
+	if err != nil || header.Name() != CONTRIBUTION_COMPONENT || PointEncoding(header.Encoding) != POINT_COMPRESSED {
+		t.Errorf("Unexpected header: %v", err)
+	}
+
+	fileName := folder + "/contrib-000.data"
+	data, err := os.ReadFile(fileName)
+	check(err)
+	check(os.WriteFile(fileName, data[:len(data)-1], 0644))
+	if err := acc.VerifyManifest(); !errors.Is(err, ErrShortRead) {
+		t.Errorf("Truncated contribution passed the manifest: %v", err)
+	}
+	if _, err := acc.TryLoadContributions(); !errors.Is(err, ErrShortRead) {
+		t.Errorf("Truncated contribution reported as: %v", err)
+	}
+
+	// A record renamed to another index
+	check(os.WriteFile(folder+"/contrib-001.data", data, 0644))
+	check(os.Remove(fileName))
+	var fe *FileError
+	if _, err := acc.TryLoadContributions(); !errors.Is(err, ErrFormat) || !errors.As(err, &fe) {
+		t.Errorf("Renamed contribution reported as: %v", err)
+	}
+}
@@ -15,9 +15,9 @@ func (self *BpAcc) Commit(elements []mcl.Fr) (mcl.G1, []mcl.Fr) {
 	if uint64(len(elements)) > self.Q {
 		panic(fmt.Sprintf("Wants to commit %d, but the accumulator supports only %d", len(elements), self.Q))
 	}
-	accPoly := fft.PolyTree(elements)
+	accPoly := polyTree(elements)
 	var digest mcl.G1
-	mcl.G1MulVec(&digest, self.PK[:len(accPoly)], accPoly)
+	mcl.G1MulVec(&digest, self.pkRange(0, uint64(len(accPoly))), accPoly)
 	return digest, accPoly
 }
 
@@ -31,8 +31,8 @@ func (self *BpAcc) MemProve(X []mcl.Fr, I []mcl.Fr) []mcl.G1 {
 	if uint64(len(X)+len(I)) > self.Q {
 		panic(fmt.Sprintf("Wants to commit %d, but the accumulator supports only %d", len(X)+len(I), self.Q))
 	}
-	complete := append(X, I...)
-	accPoly := fft.PolyTree(complete)
+	complete := append(X[:len(X):len(X)], I...)
+	accPoly := polyTree(complete)
 
 	proofs := make([]mcl.G1, len(I))
 
@@ -44,7 +44,7 @@ func (self *BpAcc) MemProve(X []mcl.Fr, I []mcl.Fr) []mcl.G1 {
 		if remainder[0].IsZero() == false {
 			panic(fmt.Sprintf("Not sure why polynomial division does not return zero."))
 		}
-		mcl.G1MulVec(&proofs[i], self.PK[:len(quotient)], quotient)
+		mcl.G1MulVec(&proofs[i], self.pkRange(0, uint64(len(quotient))), quotient)
 	}
 	return proofs
 }
@@ -53,8 +53,9 @@ func (self *BpAcc) MemVerifySingle(digest mcl.G1, I mcl.Fr, proof mcl.G1) bool {
 
 	// Compute h^{s - i}
 	var h1 mcl.G2
-	mcl.G2Mul(&h1, &self.VK[0], &I)
-	mcl.G2Sub(&h1, &self.VK[1], &h1)
+	vk1 := self.vkAt(1)
+	mcl.G2Mul(&h1, &self.H, &I)
+	mcl.G2Sub(&h1, &vk1, &h1)
 
 	P := []mcl.G1{digest, proof}
 	Q := []mcl.G2{self.Hneg, h1}
@@ -78,7 +79,7 @@ func (self *BpAcc) NonMemProve(X []mcl.Fr, I []mcl.Fr) []NonMemProof {
 		panic(fmt.Sprintf("Wants to commit %d, but the accumulator supports only %d", len(X), self.Q))
 	}
 
-	accPoly := fft.PolyTree(X)
+	accPoly := polyTree(X)
 
 	proofs := make([]NonMemProof, len(I))
 
@@ -104,7 +105,7 @@ func (self *BpAcc) NonMemProve(X []mcl.Fr, I []mcl.Fr) []NonMemProof {
 		pi := NonMemProof{}
 		pi.Alpha = alpha[0] // Since alpha contains only the const, alpha[0] is saved in pi.Alpha
 
-		mcl.G1MulVec(&pi.Beta, self.PK[:len(beta)], beta)
+		mcl.G1MulVec(&pi.Beta, self.pkRange(0, uint64(len(beta))), beta)
 		proofs[i] = pi
 	}
 	return proofs
@@ -118,11 +119,12 @@ func (self *BpAcc) NonMemVerifySingle(digest mcl.G1, y mcl.Fr, pi *NonMemProof)
 
 	var h2 mcl.G2
 
-	mcl.G2Mul(&h2, &self.VK[0], &y)
-	mcl.G2Sub(&h2, &self.VK[1], &h2)
+	vk1 := self.vkAt(1)
+	mcl.G2Mul(&h2, &self.H, &y)
+	mcl.G2Sub(&h2, &vk1, &h2)
 
 	P := []mcl.G1{g1, pi.Beta}
-	Q := []mcl.G2{self.VK[0], h2}
+	Q := []mcl.G2{self.H, h2}
 
 	var e5 mcl.GT
 	mcl.MillerLoopVec(&e5, P, Q)
@@ -152,10 +154,10 @@ func (self *BpAcc) AggMemProve(I []mcl.Fr, proofs []mcl.G1) (mcl.G1, []mcl.Fr) {
 
 func (self *BpAcc) AggMemVerify(digest mcl.G1, I []mcl.Fr, proof mcl.G1) bool {
 
-	accPoly := fft.PolyTree(I)
+	accPoly := polyTree(I)
 
 	var h1 mcl.G2
-	mcl.G2MulVec(&h1, self.VK[:len(accPoly)], accPoly)
+	mcl.G2MulVec(&h1, self.vkRange(0, uint64(len(accPoly))), accPoly)
 
 	P := []mcl.G1{digest, proof}
 	Q := []mcl.G2{self.Hneg, h1}
@@ -174,7 +176,7 @@ func (self *BpAcc) AggMemProvePoE(digest mcl.G1, I []mcl.Fr, proofs []mcl.G1) (m
 }
 
 func (self *BpAcc) AggMemVerifyPoE(digest mcl.G1, I []mcl.Fr, proof mcl.G1, Q1 mcl.G1, Q2 mcl.G2) bool {
-	I_s := fft.PolyTree(I)
+	I_s := polyTree(I)
 	return self.NiPoEVerifyG1(Q1, Q2, digest, proof, I_s)
 }
 
@@ -209,7 +211,7 @@ func (self *BpAcc) AggNonMemProve(I []mcl.Fr, proofs []NonMemProof) (mcl.G2, mcl
 		Y_i, _ = fft.PolyDiv(subProdTree[len(subProdTree)-1][0], x)
 		Y_i = PolyMulScalar(Y_i, &bezouts[i])
 		Y_i = PolyMulScalar(Y_i, &proofs[i].Alpha)
-		mcl.G2MulVec(&hY, self.VK[:len(Y_i)], Y_i)
+		mcl.G2MulVec(&hY, self.vkRange(0, uint64(len(Y_i))), Y_i)
 		mcl.G2Add(&alpha, &alpha, &hY)
 
 		var gB mcl.G1
@@ -224,8 +226,8 @@ func (self *BpAcc) AggNonMemVerify(digest mcl.G1, alphaOfS mcl.G2, betaOfS mcl.G
 
 	var h2 mcl.G2
 
-	accPoly := fft.PolyTree(I)
-	mcl.G2MulVec(&h2, self.VK[:len(accPoly)], accPoly)
+	accPoly := polyTree(I)
+	mcl.G2MulVec(&h2, self.vkRange(0, uint64(len(accPoly))), accPoly)
 
 	P := []mcl.G1{digest, betaOfS}
 	Q := []mcl.G2{alphaOfS, h2}
@@ -240,7 +242,7 @@ func (self *BpAcc) AggNonMemVerify(digest mcl.G1, alphaOfS mcl.G2, betaOfS mcl.G
 func (self *BpAcc) AggNonMemProvePoE(I []mcl.Fr, proofs []NonMemProof) (mcl.G2, mcl.G1, mcl.G1, mcl.G1, mcl.G2, []mcl.Fr) {
 	alpha, beta, I_s := self.AggNonMemProve(I, proofs)
 	var w mcl.G2
-	mcl.G2MulVec(&w, self.VK[:len(I_s)], I_s)
+	mcl.G2MulVec(&w, self.vkRange(0, uint64(len(I_s))), I_s)
 	Q1, Q2 := self.NiPoEProveG2(w, self.H, I_s)
 	return alpha, beta, Q1, Q2, w, I_s
 }
@@ -248,7 +250,7 @@ func (self *BpAcc) AggNonMemProvePoE(I []mcl.Fr, proofs []NonMemProof) (mcl.G2,
 func (self *BpAcc) AggNonMemVerifyPoE(digest mcl.G1, alphaOfS mcl.G2, betaOfS mcl.G1,
 	Q1 mcl.G1, Q2 mcl.G1, w mcl.G2, I []mcl.Fr) bool {
 
-	I_s := fft.PolyTree(I)
+	I_s := polyTree(I)
 	var status bool
 	status = self.NiPoEVerifyG2(Q1, Q2, w, self.H, I_s)
 
@@ -261,3 +263,87 @@ func (self *BpAcc) AggNonMemVerifyPoE(digest mcl.G1, alphaOfS mcl.G2, betaOfS mc
 
 	return status && e5.IsEqual(&self.IdGT)
 }
+
+// Takes the full set and a subset I of it
+// Returns the batch membership witness of I: g^{Q(s)}, where Q(x) = full(x) / I(x)
+// This is the Pi_I ZKMemProver expects, computed without the trapdoor.
+// Panics if I is empty.
+// Compute Subproduct trees |full|log^2|full| and |I|log^2|I|
+// Computes one long division
+// Computes one multi-exp of size |full| - |I| + 1
+func (self *BpAcc) ProveBatchMem(full []mcl.Fr, I []mcl.Fr) mcl.G1 {
+
+	if len(I) == 0 {
+		panic("The batch membership witness of an empty I.")
+	}
+	if uint64(len(full)) > self.Q {
+		panic(fmt.Sprintf("Wants to commit %d, but the accumulator supports only %d", len(full), self.Q))
+	}
+
+	accPoly := polyTree(full)
+	iPoly := polyTree(I)
+
+	quotient, remainder := fft.PolyDiv(accPoly, iPoly)
+	if !fft.IsPolyZero(remainder) {
+		panic(fmt.Sprintf("I is not a subset of the full set."))
+	}
+
+	var witness mcl.G1
+	mcl.G1MulVec(&witness, self.pkRange(0, uint64(len(quotient))), quotient)
+	return witness
+}
+
+// Checks e(digest, h^{-1}) e(witness, h^{I(s)}) = 1, that is the same check as AggMemVerify.
+// An empty I is rejected.
+func (self *BpAcc) VerifyBatchMem(digest mcl.G1, I []mcl.Fr, witness mcl.G1) bool {
+	if len(I) == 0 {
+		return false
+	}
+	return self.AggMemVerify(digest, I, witness)
+}
+
+// Takes two disjoint sets as inputs: X and I
+// Returns the batch non-membership witness of I: A = h^{a(s)} and B = g^{b(s)}, where a(x)X(x) + b(x)I(x) = 1
+// Panics if I is empty.
+// This is the witness ZKNonMemProver expects, computed without the trapdoor.
+// Compute Subproduct trees |X|log^2|X| and |I|log^2|I|
+// Computes one xGCD
+// Computes one G2 multi-exp of size < |I| and one G1 multi-exp of size < |X|
+func (self *BpAcc) ProveBatchNonMem(X []mcl.Fr, I []mcl.Fr) (mcl.G2, mcl.G1) {
+
+	if len(I) == 0 {
+		panic("The batch non-membership witness of an empty I.")
+	}
+	if uint64(len(X)) > self.Q || uint64(len(I)) > self.Q {
+		panic(fmt.Sprintf("Wants to commit %d and %d, but the accumulator supports only %d", len(X), len(I), self.Q))
+	}
+
+	xPoly := polyTree(X)
+	iPoly := polyTree(I)
+
+	g, a, b := fft.XGCD(xPoly, iPoly)
+	if len(g) != 1 || g[0].IsZero() {
+		panic(fmt.Sprintf("X and I are not disjoint, their GCD has degree %d.", len(g)-1))
+	}
+
+	// Make sure that aX + bI = g = 1
+	var gInv mcl.Fr
+	mcl.FrInv(&gInv, &g[0])
+	a = PolyMulScalar(a, &gInv)
+	b = PolyMulScalar(b, &gInv)
+
+	var A mcl.G2
+	var B mcl.G1
+	mcl.G2MulVec(&A, self.vkRange(0, uint64(len(a))), a)
+	mcl.G1MulVec(&B, self.pkRange(0, uint64(len(b))), b)
+	return A, B
+}
+
+// Checks e(digest, A) e(B, h^{I(s)}) = e(g, h), that is the same check as AggNonMemVerify.
+// An empty I is rejected.
+func (self *BpAcc) VerifyBatchNonMem(digest mcl.G1, I []mcl.Fr, A mcl.G2, B mcl.G1) bool {
+	if len(I) == 0 {
+		return false
+	}
+	return self.AggNonMemVerify(digest, A, B, I)
+}
@@ -0,0 +1,174 @@
+package bpacc
+
+import (
+	"fmt"
+
+	"github.com/accumulators-agg/go-poly/fft"
+	"github.com/alinush/go-mcl"
+)
+
+// Accumulator whose digest follows additions and deletions, instead of being recomputed with Commit.
+// The manager (holding S) updates the digest with a single exponentiation per batch: digest^{p(s)} on Add
+// and digest^{1/p(s)} on Delete, where p(x) is the product of (x - y) over the batch.
+// Anybody else can only add, with AddPublic, which multiplies the accumulator polynomial by p(x) and commits it with PK.
+// The accumulator polynomial is kept up to date either way; that costs field operations only.
+// The elements are expected to be distinct: Add does not check that they are not in the set already.
+type DynAcc struct {
+	acc *BpAcc
+
+	Digest mcl.G1   // g^{f(s)}
+	Size   uint64   // Number of elements, that is the degree of f
+	poly   []mcl.Fr // f(x), the product of (x - y) over the set
+}
+
+// Commits to elements, which may be empty.
+func NewDynAcc(acc *BpAcc, elements []mcl.Fr) *DynAcc {
+	self := &DynAcc{acc: acc}
+	if len(elements) == 0 {
+		self.poly = []mcl.Fr{frOne()}
+		self.Digest = acc.G
+		return self
+	}
+	self.Digest, self.poly = acc.Commit(elements)
+	self.Size = uint64(len(elements))
+	return self
+}
+
+func frOne() mcl.Fr {
+	var one mcl.Fr
+	one.SetInt64(1)
+	return one
+}
+
+// The accumulator polynomial f(x). Must not be modified.
+func (self *DynAcc) Poly() []mcl.Fr {
+	return self.poly
+}
+
+func (self *DynAcc) checkRoom(n int) {
+	if self.Size+uint64(n) > self.acc.Q {
+		panic(fmt.Sprintf("Wants to accumulate %d, but the accumulator supports only %d", self.Size+uint64(n), self.acc.Q))
+	}
+}
+
+func (self *DynAcc) checkTrapdoor() {
+	if !self.acc.HasTrapdoor() {
+		panic("Only the manager can update the digest with the trapdoor, see AddPublic.")
+	}
+}
+
+// Computes p(s), the product of (s - y) over the elements.
+func (self *BpAcc) evalAtS(elements []mcl.Fr) mcl.Fr {
+	prod := frOne()
+	var temp mcl.Fr
+	for i := range elements {
+		mcl.FrSub(&temp, &self.S, &elements[i])
+		mcl.FrMul(&prod, &prod, &temp)
+	}
+	return prod
+}
+
+// Manager only. Adds the elements with one exponentiation.
+// Computes |elements| field multiplications, plus the update of the polynomial.
+func (self *DynAcc) Add(elements []mcl.Fr) {
+	if len(elements) == 0 {
+		return
+	}
+	self.checkTrapdoor()
+	self.checkRoom(len(elements))
+
+	p := self.acc.evalAtS(elements)
+	mcl.G1Mul(&self.Digest, &self.Digest, &p)
+	self.poly = fft.PolyMul(self.poly, polyTree(elements))
+	self.Size += uint64(len(elements))
+}
+
+// Manager only. Deletes the elements with one exponentiation.
+// Panics if one of them is not in the set.
+func (self *DynAcc) Delete(elements []mcl.Fr) {
+	if len(elements) == 0 {
+		return
+	}
+	self.checkTrapdoor()
+	if uint64(len(elements)) > self.Size {
+		panic(fmt.Sprintf("Wants to delete %d, but the set holds only %d", len(elements), self.Size))
+	}
+
+	quotient, remainder := fft.PolyDiv(self.poly, polyTree(elements))
+	if !fft.IsPolyZero(remainder) {
+		panic(fmt.Sprintf("Not all the elements to delete are in the set."))
+	}
+
+	p := self.acc.evalAtS(elements)
+	mcl.FrInv(&p, &p)
+	mcl.G1Mul(&self.Digest, &self.Digest, &p)
+	self.poly = quotient
+	self.Size -= uint64(len(elements))
+}
+
+// Adds the elements without the trapdoor.
+// Multiplies f(x) by the polynomial of the elements and computes a multi-exp of size |set| + |elements| + 1.
+func (self *DynAcc) AddPublic(elements []mcl.Fr) {
+	if len(elements) == 0 {
+		return
+	}
+	self.checkRoom(len(elements))
+
+	self.poly = fft.PolyMul(self.poly, polyTree(elements))
+	self.Size += uint64(len(elements))
+	mcl.G1MulVec(&self.Digest, self.acc.pkRange(0, uint64(len(self.poly))), self.poly)
+}
+
+// Manager only. Same as Add, but one element at a time, thus with one exponentiation per element.
+// Returns the steps to publish, for the holders to update their witnesses (see MemWitnessUpdate).
+func (self *DynAcc) AddWithUpdates(elements []mcl.Fr) []DigestUpdate {
+	updates := make([]DigestUpdate, len(elements))
+	for i := range elements {
+		updates[i].Element = elements[i]
+		updates[i].Old = self.Digest
+		self.Add(elements[i : i+1])
+		updates[i].New = self.Digest
+	}
+	return updates
+}
+
+// Manager only. Same as Delete, but one element at a time.
+func (self *DynAcc) DeleteWithUpdates(elements []mcl.Fr) []DigestUpdate {
+	updates := make([]DigestUpdate, len(elements))
+	for i := range elements {
+		updates[i].Element = elements[i]
+		updates[i].Deleted = true
+		updates[i].Old = self.Digest
+		self.Delete(elements[i : i+1])
+		updates[i].New = self.Digest
+	}
+	return updates
+}
+
+// Manager only. Same as Add, and returns the batch update for the holders (see MemWitnessBatchUpdate).
+// Computes |elements| + 1 more exponentiations.
+func (self *DynAcc) AddWithBatchUpdate(elements []mcl.Fr) BatchUpdate {
+	self.checkTrapdoor()
+	update := BatchUpdate{Elements: append([]mcl.Fr{}, elements...), Omega: self.omegas(len(elements))}
+	self.Add(elements)
+	return update
+}
+
+// Manager only. Same as Delete, and returns the batch update for the holders.
+func (self *DynAcc) DeleteWithBatchUpdate(elements []mcl.Fr) BatchUpdate {
+	self.Delete(elements)
+	return BatchUpdate{Elements: append([]mcl.Fr{}, elements...), Deleted: true, Omega: self.omegas(len(elements))}
+}
+
+// Digest^{s^i} for i = 0 .. k
+func (self *DynAcc) omegas(k int) []mcl.G1 {
+	omega := make([]mcl.G1, k+1)
+	omega[0] = self.Digest
+	a := self.acc.S
+	for i := 1; i <= k; i++ {
+		mcl.G1Mul(&omega[i], &self.Digest, &a)
+		mcl.FrMul(&a, &a, &self.acc.S)
+	}
+	a.Clear()
+	return omega
+}
@@ -0,0 +1,60 @@
+package bpacc
+
+import (
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestDynAcc(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 6, "xyz")
+	elements := PopulateRandom(40)
+
+	manager := NewDynAcc(&acc, elements[:10])
+	public := NewDynAcc(&acc, elements[:10])
+
+	manager.Add(elements[10:30])
+	public.AddPublic(elements[10:30])
+	expected, _ := acc.Commit(elements[:30])
+	if !manager.Digest.IsEqual(&expected) || !public.Digest.IsEqual(&expected) {
+		t.Errorf("Digest after additions differs from Commit")
+	}
+	if manager.Size != 30 || public.Size != 30 || len(manager.Poly()) != 31 || len(public.Poly()) != 31 {
+		t.Errorf("Sizes %d and %d after additions", manager.Size, public.Size)
+	}
+
+	// Delete a few from the middle, then verify a membership proof against the tracked digest
+	manager.Delete(elements[5:15])
+	remaining := append(append([]mcl.Fr{}, elements[:5]...), elements[15:30]...)
+	expected, _ = acc.Commit(remaining)
+	if !manager.Digest.IsEqual(&expected) || manager.Size != 20 {
+		t.Errorf("Digest after deletions differs from Commit")
+	}
+	proofs := acc.MemProve(remaining[1:], remaining[:1])
+	if !acc.MemVerifySingle(manager.Digest, remaining[0], proofs[0]) {
+		t.Errorf("Membership proof did not verify against the tracked digest")
+	}
+
+	// The polynomial follows the deletions, thus the public path picks up from there
+	manager.AddPublic(elements[30:32])
+	expected, _ = acc.Commit(append(remaining, elements[30:32]...))
+	if !manager.Digest.IsEqual(&expected) {
+		t.Errorf("Public addition after deletions differs from Commit")
+	}
+
+	empty := NewDynAcc(&acc, nil)
+	empty.Add(elements[:3])
+	expected, _ = acc.Commit(elements[:3])
+	if !empty.Digest.IsEqual(&expected) {
+		t.Errorf("Additions to the empty set differ from Commit")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Deleting an element outside of the set did not panic")
+		}
+	}()
+	manager.Delete(elements[35:36])
+}
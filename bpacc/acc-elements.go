@@ -0,0 +1,72 @@
+package bpacc
+
+import (
+	"encoding/binary"
+
+	"github.com/alinush/go-mcl"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Canonical mapping of application identifiers (serial numbers, public keys, URLs, ...) to accumulator elements.
+// The procedure (also described in the Readme) is:
+//
+//	msg     = ELEMENTS_LABEL || uint64_be(len(domain)) || domain || data
+//	element = blake2b-512(msg) as a big-endian integer, mod r
+//
+// The 64 bytes are reduced at once (wide reduction), thus the bias of the elements is below 2^-256.
+// The domain separates the applications: the same bytes under two domains give unrelated elements.
+// Unlike SeedToFr, there is no fallback to small integers.
+const ELEMENTS_LABEL = "BPACC-V1-BLS12381-ELEMENTS"
+
+// Maps data to an element of the accumulator, under domain. Panics if domain is empty.
+func ElementFromBytes(domain string, data []byte) mcl.Fr {
+	if len(domain) == 0 {
+		panic("Elements need a domain.")
+	}
+	hash, err := blake2b.New512(nil)
+	check(err)
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(domain)))
+	hash.Write([]byte(ELEMENTS_LABEL))
+	hash.Write(length[:])
+	hash.Write([]byte(domain))
+	hash.Write(data)
+
+	var element mcl.Fr
+	check(element.SetBigEndianMod(hash.Sum(nil)))
+	return element
+}
+
+// Same as ElementFromBytes, for each of data, in the same order.
+func ElementsFromBytes(domain string, data [][]byte) []mcl.Fr {
+	elements := make([]mcl.Fr, len(data))
+	for i := range data {
+		elements[i] = ElementFromBytes(domain, data[i])
+	}
+	return elements
+}
+
+// Same as Commit, on the elements of data under domain.
+func (self *BpAcc) CommitBytes(domain string, data [][]byte) (mcl.G1, []mcl.Fr) {
+	return self.Commit(ElementsFromBytes(domain, data))
+}
+
+// Same as MemProve, on the elements of X and I under domain.
+func (self *BpAcc) MemProveBytes(domain string, X [][]byte, I [][]byte) []mcl.G1 {
+	return self.MemProve(ElementsFromBytes(domain, X), ElementsFromBytes(domain, I))
+}
+
+// Same as MemVerifySingle, on the element of data under domain.
+func (self *BpAcc) MemVerifySingleBytes(digest mcl.G1, domain string, data []byte, proof mcl.G1) bool {
+	return self.MemVerifySingle(digest, ElementFromBytes(domain, data), proof)
+}
+
+// Same as NonMemProve, on the elements of X and I under domain.
+func (self *BpAcc) NonMemProveBytes(domain string, X [][]byte, I [][]byte) []NonMemProof {
+	return self.NonMemProve(ElementsFromBytes(domain, X), ElementsFromBytes(domain, I))
+}
+
+// Same as NonMemVerifySingle, on the element of data under domain.
+func (self *BpAcc) NonMemVerifySingleBytes(digest mcl.G1, domain string, data []byte, pi *NonMemProof) bool {
+	return self.NonMemVerifySingle(digest, ElementFromBytes(domain, data), pi)
+}
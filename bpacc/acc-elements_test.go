@@ -0,0 +1,54 @@
+package bpacc
+
+import (
+	"testing"
+)
+
+func TestElementsFromBytes(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 5, "xyz")
+
+	serials := [][]byte{[]byte("serial-0001"), []byte("serial-0002"), []byte("serial-0003"), []byte("serial-0004")}
+	revoked := [][]byte{[]byte("serial-0005"), []byte("serial-0006")}
+
+	digest, _ := acc.CommitBytes("serials", serials)
+	memProofs := acc.MemProveBytes("serials", serials[1:], serials[:1])
+	if !acc.MemVerifySingleBytes(digest, "serials", serials[0], memProofs[0]) {
+		t.Errorf("Membership proof did not verify")
+	}
+	if acc.MemVerifySingleBytes(digest, "urls", serials[0], memProofs[0]) {
+		t.Errorf("Membership proof verified under another domain")
+	}
+	nonMemProofs := acc.NonMemProveBytes("serials", serials, revoked)
+	if !acc.NonMemVerifySingleBytes(digest, "serials", revoked[1], &nonMemProofs[1]) {
+		t.Errorf("Non-membership proof did not verify")
+	}
+
+	// The domain is length prefixed: moving bytes between the domain and the data changes the element
+	a := ElementFromBytes("ab", []byte("c"))
+	b := ElementFromBytes("a", []byte("bc"))
+	if a.IsEqual(&b) {
+		t.Errorf("Domain and data are ambiguous")
+	}
+	elements := ElementsFromBytes("serials", serials)
+	for i := range elements {
+		single := ElementFromBytes("serials", serials[i])
+		if !elements[i].IsEqual(&single) {
+			t.Errorf("Batch element %d differs", i)
+		}
+	}
+
+	// Known answer, from the procedure of the Readme
+	wants := "1b6bf0e602dc020496408dab5917d521aa996b434b72aede90ca3b01fb6a1ef1"
+	if got := elements[0].GetString(16); got != wants {
+		t.Errorf("Element of serial-0001 is %s, wants %s", got, wants)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Empty domain did not panic")
+		}
+	}()
+	ElementFromBytes("", serials[0])
+}
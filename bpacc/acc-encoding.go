@@ -0,0 +1,160 @@
+package bpacc
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Point encodings of the parameter files.
+// The encoding of a file is recorded in its header, thus a folder is always read back with the encoding it was written with.
+// Options.Encoding selects the encoding of the files an accumulator writes and that of its proofs: their ByteSize and
+// the points hashed into the Fiat-Shamir and PoE challenges, thus the prover and the verifier must select the same one.
+// GetG1ByteSize and GetG2ByteSize report its sizes. Only the ceremony contributions always use the compressed form.
+type PointEncoding uint8
+
+const (
+	// mcl Serialize, 48/96 bytes. Decoding takes a square root.
+	POINT_COMPRESSED PointEncoding = iota
+	// The affine x and y, 96/192 bytes. Decoding checks that the point is on the curve (and its order, if mcl is told to).
+	POINT_UNCOMPRESSED
+	// Same bytes as POINT_UNCOMPRESSED, decoded without any check.
+	// Only meant for the files we produce ourselves: they are decoded without checks only from the folders listed
+	// in Options.TrustedFolders of the loading accumulator, otherwise they are decoded as POINT_UNCOMPRESSED.
+	POINT_TRUSTED
+)
+
+// Sizes of the compressed form
+const G1_COMPRESSED_SIZE = 48
+const G2_COMPRESSED_SIZE = 96
+
+func (self PointEncoding) String() string {
+	switch self {
+	case POINT_COMPRESSED:
+		return "compressed"
+	case POINT_UNCOMPRESSED:
+		return "uncompressed"
+	case POINT_TRUSTED:
+		return "trusted"
+	}
+	return fmt.Sprintf("PointEncoding(%d)", uint8(self))
+}
+
+func (self PointEncoding) Valid() bool {
+	return self <= POINT_TRUSTED
+}
+
+func (self PointEncoding) G1Size() int {
+	if self == POINT_COMPRESSED {
+		return G1_COMPRESSED_SIZE
+	}
+	return 2 * G1_COMPRESSED_SIZE
+}
+
+func (self PointEncoding) G2Size() int {
+	if self == POINT_COMPRESSED {
+		return G2_COMPRESSED_SIZE
+	}
+	return 2 * G2_COMPRESSED_SIZE
+}
+
+// The encoding used to decode a file of the folder of self, written with encoding.
+// The header is controlled by whoever wrote the file, and Options.Encoding only selects what self writes and proves,
+// thus trusted files are checked unless the loading accumulator lists their folder in Options.TrustedFolders.
+func (self *BpAcc) reading(encoding PointEncoding) PointEncoding {
+	if encoding == POINT_TRUSTED && !self.trustsFolder() {
+		return POINT_UNCOMPRESSED
+	}
+	return encoding
+}
+
+func (self *BpAcc) trustsFolder() bool {
+	folder, err := filepath.Abs(self.folderPath)
+	if err != nil || self.folderPath == "" {
+		return false
+	}
+	for _, trusted := range self.opts.TrustedFolders {
+		if path, err := filepath.Abs(trusted); err == nil && path == folder {
+			return true
+		}
+	}
+	return false
+}
+
+func (self PointEncoding) EncodeG1(x *mcl.G1) []byte {
+	if self == POINT_COMPRESSED {
+		return x.Serialize()
+	}
+	return x.SerializeUncompressed()
+}
+
+func (self PointEncoding) EncodeG2(x *mcl.G2) []byte {
+	if self == POINT_COMPRESSED {
+		return x.Serialize()
+	}
+	return x.SerializeUncompressed()
+}
+
+func (self PointEncoding) DecodeG1(x *mcl.G1, buf []byte) error {
+	switch self {
+	case POINT_COMPRESSED:
+		return x.Deserialize(buf)
+	case POINT_UNCOMPRESSED:
+		return x.DeserializeUncompressed(buf)
+	}
+	if len(buf) != self.G1Size() {
+		return fmt.Errorf("%d bytes, wants %d", len(buf), self.G1Size())
+	}
+	if isZeroEncoding(buf) {
+		x.Clear()
+		return nil
+	}
+	// Same as DeserializeUncompressed, but without IsValid
+	if err := x.X.Deserialize(buf[:G1_COMPRESSED_SIZE]); err != nil {
+		return err
+	}
+	if err := x.Y.Deserialize(buf[G1_COMPRESSED_SIZE:]); err != nil {
+		return err
+	}
+	x.Z.SetInt64(1)
+	return nil
+}
+
+func (self PointEncoding) DecodeG2(x *mcl.G2, buf []byte) error {
+	switch self {
+	case POINT_COMPRESSED:
+		return x.Deserialize(buf)
+	case POINT_UNCOMPRESSED:
+		return x.DeserializeUncompressed(buf)
+	}
+	if len(buf) != self.G2Size() {
+		return fmt.Errorf("%d bytes, wants %d", len(buf), self.G2Size())
+	}
+	if isZeroEncoding(buf) {
+		x.Clear()
+		return nil
+	}
+	if err := x.X.Deserialize(buf[:G2_COMPRESSED_SIZE]); err != nil {
+		return err
+	}
+	if err := x.Y.Deserialize(buf[G2_COMPRESSED_SIZE:]); err != nil {
+		return err
+	}
+	x.Z.D[0].SetInt64(1)
+	x.Z.D[1].Clear()
+	return nil
+}
+
+// The point at infinity, as written by SerializeUncompressed.
+func isZeroEncoding(buf []byte) bool {
+	if buf[0] != mcl.ZERO_HEADER {
+		return false
+	}
+	for _, b := range buf[1:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
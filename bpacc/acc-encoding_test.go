@@ -0,0 +1,194 @@
+package bpacc
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestPointEncoding(t *testing.T) {
+
+	var g, g2, zero mcl.G1
+	var h, h2, zeroH mcl.G2
+	g.Random()
+	h.Random()
+	for _, encoding := range []PointEncoding{POINT_COMPRESSED, POINT_UNCOMPRESSED, POINT_TRUSTED} {
+		data := encoding.EncodeG1(&g)
+		if len(data) != encoding.G1Size() {
+			t.Errorf("%s: G1 takes %d bytes, wants %d", encoding, len(data), encoding.G1Size())
+		}
+		if err := encoding.DecodeG1(&g2, data); err != nil || !g2.IsEqual(&g) {
+			t.Errorf("%s: G1 round trip failed: %v", encoding, err)
+		}
+		if err := encoding.DecodeG1(&g2, encoding.EncodeG1(&zero)); err != nil || !g2.IsZero() {
+			t.Errorf("%s: G1 zero round trip failed: %v", encoding, err)
+		}
+
+		data = encoding.EncodeG2(&h)
+		if len(data) != encoding.G2Size() {
+			t.Errorf("%s: G2 takes %d bytes, wants %d", encoding, len(data), encoding.G2Size())
+		}
+		if err := encoding.DecodeG2(&h2, data); err != nil || !h2.IsEqual(&h) {
+			t.Errorf("%s: G2 round trip failed: %v", encoding, err)
+		}
+		if err := encoding.DecodeG2(&h2, encoding.EncodeG2(&zeroH)); err != nil || !h2.IsZero() {
+			t.Errorf("%s: G2 zero round trip failed: %v", encoding, err)
+		}
+	}
+}
+
+func TestKeyGenLoadEncodings(t *testing.T) {
+
+	L := uint64(4)
+
+	var direct BpAcc
+	direct.KeyGen(8, L, "xyz", t.TempDir())
+
+	for _, encoding := range []PointEncoding{POINT_COMPRESSED, POINT_UNCOMPRESSED, POINT_TRUSTED} {
+		acc := NewBpAcc(Options{Encoding: encoding})
+		if acc.GetG1ByteSize() != encoding.G1Size() || acc.GetG2ByteSize() != encoding.G2Size() {
+			t.Errorf("%s: sizes %d and %d", encoding, acc.GetG1ByteSize(), acc.GetG2ByteSize())
+		}
+
+		folder := t.TempDir()
+		acc.KeyGen(8, L, "xyz", folder)
+
+		header, err := readFileHeader(folder + "/vrk-00.data")
+		if err != nil || PointEncoding(header.Encoding) != encoding {
+			t.Fatalf("%s: header of vrk-00 reports %d: %v", encoding, header.Encoding, err)
+		}
+		info, err := os.Stat(folder + "/vrk-00.data")
+		if err != nil || info.Size() != int64(HEADER_SIZE)+int64(header.Stop-header.Start)*int64(encoding.G2Size()) {
+			t.Errorf("%s: vrk-00 has an unexpected size: %v", encoding, err)
+		}
+
+		// The encoding of the files wins over Options.Encoding
+		var loaded BpAcc
+		if err := loaded.TryKeyGenLoad(8, L, "xyz", folder); err != nil {
+			t.Fatalf("%s: did not load: %s", encoding, err)
+		}
+		for i := uint64(0); i <= direct.Q; i++ {
+			if !loaded.PK[i].IsEqual(&direct.PK[i]) || !loaded.VK[i].IsEqual(&direct.VK[i]) ||
+				!loaded.PedVKAlpha[i].IsEqual(&direct.PedVKAlpha[i]) {
+				t.Fatalf("%s: power %d differs", encoding, i)
+			}
+		}
+		if !loaded.PedH.IsEqual(&direct.PedH) || !loaded.B[0].IsEqual(&direct.B[0]) {
+			t.Errorf("%s: trapdoors.data differs", encoding)
+		}
+
+		var mapped BpAcc
+		if err := mapped.TryKeyGenLoadMapped(8, L, folder, 1); err != nil {
+			t.Fatalf("%s: did not map: %s", encoding, err)
+		}
+		pk, err := mapped.PKRange(0, mapped.Q+1)
+		if err != nil {
+			t.Fatalf("%s: mapped powers did not decode: %s", encoding, err)
+		}
+		for i := range pk {
+			if !pk[i].IsEqual(&direct.PK[i]) {
+				t.Fatalf("%s: mapped power %d differs", encoding, i)
+			}
+		}
+		mapped.Close()
+	}
+}
+
+// The proofs follow Options.Encoding as well, their size and their challenges.
+func TestProofEncodings(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 4, "xyz")
+	elements := PopulateRandom(8)
+	X, I := elements[:4], elements[4:]
+	digest, _ := acc.Commit(elements)
+	proofs := acc.MemProve(X, I)
+
+	for _, encoding := range []PointEncoding{POINT_COMPRESSED, POINT_UNCOMPRESSED} {
+		acc.SetOptions(Options{Encoding: encoding})
+		proof, Q1, Q2, _ := acc.AggMemProvePoE(digest, I, proofs)
+		if !acc.AggMemVerifyPoE(digest, I, proof, Q1, Q2) {
+			t.Errorf("%s: PoE did not verify", encoding)
+		}
+		other := POINT_UNCOMPRESSED - encoding
+		acc.SetOptions(Options{Encoding: other})
+		if acc.AggMemVerifyPoE(digest, I, proof, Q1, Q2) {
+			t.Errorf("%s: PoE verified with the %s challenge", encoding, other)
+		}
+
+		var degProof ZKDegCheckProof
+		if degProof.ByteSize(encoding) != uint64(8+3*encoding.G2Size()) {
+			t.Errorf("%s: degree check proof of %d bytes", encoding, degProof.ByteSize(encoding))
+		}
+	}
+}
+
+// Trusted files are only decoded without checks from the folders the loading accumulator trusts.
+func TestTrustedEncoding(t *testing.T) {
+
+	folder := t.TempDir()
+	acc := NewBpAcc(Options{Encoding: POINT_TRUSTED})
+	acc.KeyGen(8, 3, "xyz", folder)
+
+	// Move the first point of vrk-00 off the curve and keep the manifest in sync
+	fileName := folder + "/vrk-00.data"
+	data, err := os.ReadFile(fileName)
+	check(err)
+	data[HEADER_SIZE+G2_COMPRESSED_SIZE] ^= 1
+	check(os.WriteFile(fileName, data, 0644))
+	acc.SaveManifest()
+
+	trusting := NewBpAcc(Options{TrustedFolders: []string{folder + "/"}})
+	if err := trusting.TryKeyGenLoad(8, 3, "xyz", folder); err != nil {
+		t.Errorf("Trusted folder was checked: %s", err)
+	}
+
+	// Writing trusted files does not make the foreign ones trusted
+	var loaded BpAcc
+	if err := loaded.TryKeyGenLoad(8, 3, "xyz", folder); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("Corrupted folder that claims to be trusted reported as: %v", err)
+	}
+	other := NewBpAcc(Options{TrustedFolders: []string{t.TempDir()}})
+	if err := other.TryKeyGenLoad(8, 3, "xyz", folder); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("Corrupted folder trusted through another folder reported as: %v", err)
+	}
+
+	// The mapped storage reports the bad point when its chunk is decoded, every time
+	var mapped BpAcc
+	if err := mapped.TryKeyGenLoadMapped(8, 3, folder, 1); err != nil {
+		t.Fatalf("Mapping decoded the points: %s", err)
+	}
+	defer mapped.Close()
+	for k := 0; k < 2; k++ {
+		if _, err := mapped.VKRange(0, 2); !errors.Is(err, ErrInvalidPoint) {
+			t.Errorf("Bad mapped point reported as: %v", err)
+		}
+	}
+	if _, err := mapped.VKAt(0); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("Bad mapped point reported as: %v", err)
+	}
+	if _, err := mapped.PKRange(0, mapped.Q+1); err != nil {
+		t.Errorf("Good mapped points reported as: %s", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Bad mapped point did not panic in MemVerifySingle")
+			}
+		}()
+		var digest, proof mcl.G1
+		var y mcl.Fr
+		mapped.MemVerifySingle(digest, y, proof)
+	}()
+
+	trustingMapped := NewBpAcc(Options{TrustedFolders: []string{folder}})
+	if err := trustingMapped.TryKeyGenLoadMapped(8, 3, folder, 1); err != nil {
+		t.Fatalf("Trusted folder did not map: %s", err)
+	}
+	defer trustingMapped.Close()
+	if _, err := trustingMapped.VKRange(0, 2); err != nil {
+		t.Errorf("Trusted folder was checked when mapped: %s", err)
+	}
+}
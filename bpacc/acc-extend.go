@@ -0,0 +1,220 @@
+package bpacc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Folder, inside the parameter folder, where TryExtend writes the files before they replace the old ones
+const EXTEND_STAGING = "/extend.staging"
+
+// Extends the setup of folderPath to ELL L without recomputing the powers that are already there.
+// The missing powers are computed from the manager trapdoor and appended as Options.Segments new segment files (prk-16, prk-17, ...),
+// then ELL is updated in trapdoors.data or public.data and in the manifest; the old segments are left as they are.
+// The trapdoor comes from trapdoors.data (KeyGen) or, when secretPath is not empty, from the file written by KeyGenPublic,
+// which holds no ELL and is not rewritten.
+// A setup from the ceremony has no trapdoor: the new powers start from the values of CeremonyInit, and every participant
+// then raises them with ExtendContribution, using the secret kept by ContributeExtendable.
+// On return the accumulator holds the extended setup, as if it was loaded with KeyGenLoad(L).
+// The new and rewritten files are staged in EXTEND_STAGING and moved into the folder once the new manifest is written,
+// thus the manifest is the commit point: an interrupted extension is finished or dropped by RecoverExtend,
+// which TryExtend runs first.
+func (self *BpAcc) Extend(ncores uint8, L uint64, folderPath string, secretPath string) {
+	check(self.TryExtend(ncores, L, folderPath, secretPath))
+}
+
+func (self *BpAcc) TryExtend(ncores uint8, L uint64, folderPath string, secretPath string) error {
+	self.setWorkers(ncores)
+	if err := self.RecoverExtend(folderPath); err != nil {
+		return err
+	}
+	manifest, err := self.LoadManifest()
+	if err != nil {
+		return err
+	}
+	oldEll := manifest.ELL
+	if L <= oldEll {
+		return &FileError{folderPath, fmt.Errorf("%w: setup already has ELL %d, cannot extend to %d", ErrEllMismatch, oldEll, L)}
+	}
+	first := 0
+	for _, entry := range manifest.Files {
+		if entry.Component == componentName(PRK_NAME) && int(entry.Segment) >= first {
+			first = int(entry.Segment) + 1
+		}
+	}
+	segments, err := self.segments()
+	if err == nil && first+segments > MAX_SEGMENTS {
+		err = fmt.Errorf("%w: no room for %d more segments after segment %d", ErrFormat, segments, first-1)
+	}
+	if err != nil {
+		return &FileError{folderPath, err}
+	}
+	if err := self.VerifyManifest(); err != nil {
+		return err
+	}
+
+	// Load the current setup along with its trapdoor
+	self.Init(oldEll, "", folderPath)
+	_, err = os.Stat(folderPath + TRAPDOORNAME)
+	hasTrapdoor := err == nil
+	contributions, err := self.contributionFiles()
+	if err != nil {
+		return err
+	}
+	ceremony := !hasTrapdoor && secretPath == "" && len(contributions) > 0
+	if hasTrapdoor {
+		err = self.loadTrapdoor(oldEll)
+	} else if secretPath != "" || ceremony {
+		err = self.loadPublic(oldEll)
+	} else {
+		err = &FileError{folderPath + TRAPDOORNAME, fmt.Errorf("%w: the manager trapdoor is needed to extend the setup", ErrMissingFile)}
+	}
+	if err != nil {
+		return err
+	}
+	if err := self.loadSegments(); err != nil {
+		return err
+	}
+	if ceremony {
+		self.S.SetInt64(1)
+		self.Alpha.SetInt64(1)
+		defer self.ClearTrapdoors()
+	} else if !hasTrapdoor {
+		if err := self.loadManagerSecret(secretPath); err != nil {
+			return err
+		}
+		defer self.ClearTrapdoors()
+	}
+
+	// Compute and save the new powers
+	oldNum := self.Q + 1
+	self.grow(L)
+	self.powersGen(oldNum, self.Q+1)
+
+	staging := folderPath + EXTEND_STAGING
+	if err := os.Mkdir(staging, os.ModePerm); err != nil {
+		return &FileError{staging, err}
+	}
+	committed := false
+	defer func() {
+		self.folderPath = folderPath
+		if !committed {
+			os.RemoveAll(staging)
+		}
+	}()
+
+	self.folderPath = staging
+	if err := self.writeSegments(first, oldNum); err != nil {
+		return err
+	}
+	if hasTrapdoor {
+		err = self.saveTrapdoor()
+	} else {
+		err = self.savePublic()
+	}
+	if err != nil {
+		return err
+	}
+
+	// The old segments keep their entries, only the staged files are hashed
+	self.folderPath = folderPath
+	files, err := parameterFilesIn(folderPath, staging)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]ManifestEntry)
+	for _, entry := range manifest.Files {
+		known[filepath.Join(folderPath, entry.Name)] = entry
+	}
+	if err := self.writeManifest(files, known); err != nil {
+		return err
+	}
+	committed = true
+	return moveStaged(folderPath)
+}
+
+// Finishes or drops an extension that TryExtend left in EXTEND_STAGING.
+// When every staged file matches the manifest, the manifest of the extension was written and the files are moved into the folder;
+// otherwise the extension never took effect and the staged files are removed. A folder without staged files is left alone.
+func (self *BpAcc) RecoverExtend(folderPath string) error {
+	self.folderPath = folderPath
+	staging := folderPath + EXTEND_STAGING
+	entries, err := os.ReadDir(staging)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return &FileError{staging, err}
+	}
+
+	manifest, err := self.LoadManifest()
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]ManifestEntry)
+	for _, entry := range manifest.Files {
+		listed[entry.Name] = entry
+	}
+	committed := true
+	for _, e := range entries {
+		entry, ok := listed[e.Name()]
+		if !ok {
+			committed = false
+			break
+		}
+		hash, size, err := hashFile(staging + "/" + e.Name())
+		if err != nil {
+			return err
+		}
+		if size != entry.Size || hash != entry.Hash {
+			committed = false
+			break
+		}
+	}
+
+	if committed {
+		self.logln("Finishing the extension staged in:", staging)
+		return moveStaged(folderPath)
+	}
+	self.logln("Dropping the extension staged in:", staging)
+	if err := os.RemoveAll(staging); err != nil {
+		return &FileError{staging, err}
+	}
+	return nil
+}
+
+// Moves the staged files into the folder, over the old ones, and removes the staging folder.
+func moveStaged(folderPath string) error {
+	staging := folderPath + EXTEND_STAGING
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return &FileError{staging, err}
+	}
+	for _, e := range entries {
+		if err := os.Rename(staging+"/"+e.Name(), folderPath+"/"+e.Name()); err != nil {
+			return &FileError{staging + "/" + e.Name(), err}
+		}
+	}
+	if err := os.Remove(staging); err != nil {
+		return &FileError{staging, err}
+	}
+	return nil
+}
+
+// Resizes the components to ELL L, keeping the powers already computed.
+func (self *BpAcc) grow(L uint64) {
+	self.ELL = L
+	self.Q = uint64(1) << self.ELL
+
+	extra := int(self.Q+1) - len(self.PK)
+	self.PK = append(self.PK, make([]mcl.G1, extra)...)
+	self.VK = append(self.VK, make([]mcl.G2, extra)...)
+	self.VKAlpha = append(self.VKAlpha, make([]mcl.G2, extra)...)
+	self.PedVK = append(self.PedVK, make([]mcl.G2, extra)...)
+	self.PedVKAlpha = append(self.PedVKAlpha, make([]mcl.G2, extra)...)
+}
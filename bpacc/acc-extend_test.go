@@ -0,0 +1,187 @@
+package bpacc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestExtend(t *testing.T) {
+
+	small, large := uint64(3), uint64(6)
+
+	// Seeded setup: the extension has to match a setup generated at the larger ELL directly.
+	folder := t.TempDir()
+	var acc BpAcc
+	acc.KeyGen(8, small, "xyz", folder)
+	old, err := os.ReadFile(folder + "/vrk-03.data")
+	check(err)
+	acc.Extend(8, large, folder, "")
+
+	// The old segments are left as they are
+	if data, err := os.ReadFile(folder + "/vrk-03.data"); err != nil || !bytes.Equal(data, old) {
+		t.Errorf("Old segment rewritten: %v", err)
+	}
+
+	var direct, loaded BpAcc
+	direct.KeyGen(8, large, "xyz", t.TempDir())
+	if err := loaded.TryKeyGenLoad(8, large, "xyz", folder); err != nil {
+		t.Fatalf("Extended folder did not load: %s", err)
+	}
+	for i := uint64(0); i <= direct.Q; i++ {
+		if !loaded.PK[i].IsEqual(&direct.PK[i]) || !loaded.VK[i].IsEqual(&direct.VK[i]) ||
+			!loaded.VKAlpha[i].IsEqual(&direct.VKAlpha[i]) || !loaded.PedVK[i].IsEqual(&direct.PedVK[i]) ||
+			!loaded.PedVKAlpha[i].IsEqual(&direct.PedVKAlpha[i]) || !acc.PK[i].IsEqual(&direct.PK[i]) {
+			t.Fatalf("Power %d differs", i)
+		}
+	}
+	if err := loaded.TryKeyGenLoad(8, small, "xyz", folder); err != nil {
+		t.Errorf("Prefix of the extended folder did not load: %s", err)
+	}
+
+	if err := acc.TryExtend(8, large, folder, ""); !errors.Is(err, ErrEllMismatch) {
+		t.Errorf("Extending to the same ELL reported as: %v", err)
+	}
+
+	// Too many segments are caught before the folder is read
+	crowded := NewBpAcc(Options{Segments: MAX_SEGMENTS})
+	check(os.Rename(folder+"/vrk-03.data", folder+"/vrk-03.moved"))
+	if err := crowded.TryExtend(8, large+1, folder, ""); !errors.Is(err, ErrFormat) || errors.Is(err, ErrMissingFile) {
+		t.Errorf("Too many segments reported as: %v", err)
+	}
+	check(os.Rename(folder+"/vrk-03.moved", folder+"/vrk-03.data"))
+
+	// Trapdoor-free folder with the manager secret kept aside
+	folder = t.TempDir()
+	secret := t.TempDir() + "/secret.data"
+	acc = BpAcc{}
+	acc.KeyGenPublic(8, small, folder, secret)
+	oldSecret, err := os.ReadFile(secret)
+	check(err)
+	if err := acc.TryExtend(8, large, folder, ""); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("Extending without the trapdoor reported as: %v", err)
+	}
+	acc.Extend(8, large, folder, secret)
+	if acc.HasTrapdoor() {
+		t.Errorf("Trapdoor left in memory")
+	}
+	if data, err := os.ReadFile(secret); err != nil || !bytes.Equal(data, oldSecret) {
+		t.Errorf("Manager secret rewritten: %v", err)
+	}
+
+	loaded = BpAcc{}
+	if err := loaded.TryKeyGenLoadPublic(8, large, folder); err != nil {
+		t.Fatalf("Extended public folder did not load: %s", err)
+	}
+	if err := loaded.VerifyParams(); err != nil {
+		t.Errorf("Extended public folder: %s", err)
+	}
+	loaded.LoadManagerSecret(secret)
+}
+
+// The states an interrupted extension can leave behind, before and after its manifest is written.
+func TestRecoverExtend(t *testing.T) {
+
+	small, large := uint64(3), uint64(5)
+	folder := t.TempDir()
+	var acc BpAcc
+	acc.KeyGen(8, small, "xyz", folder)
+	acc.Extend(8, large, folder, "")
+	if _, err := os.Stat(folder + EXTEND_STAGING); !os.IsNotExist(err) {
+		t.Errorf("Staging folder left behind: %v", err)
+	}
+
+	// Interrupted before the manifest: the staged files do not match it and are dropped
+	staging := folder + EXTEND_STAGING
+	check(os.Mkdir(staging, os.ModePerm))
+	data, err := os.ReadFile(folder + "/prk-00.data")
+	check(err)
+	data[len(data)-1] ^= 1
+	check(os.WriteFile(staging+"/prk-00.data", data, 0644))
+	check(os.WriteFile(staging+"/prk-99.data", []byte("partial"), 0644))
+	check(acc.RecoverExtend(folder))
+	var loaded BpAcc
+	if err := loaded.TryKeyGenLoad(8, large, "xyz", folder); err != nil {
+		t.Errorf("Dropped extension left the folder broken: %s", err)
+	}
+	if _, err := os.Stat(folder + "/prk-99.data"); !os.IsNotExist(err) {
+		t.Errorf("Dropped file moved into the folder: %v", err)
+	}
+
+	// Interrupted while moving the files after the manifest: they are moved in
+	check(os.Mkdir(staging, os.ModePerm))
+	for _, name := range []string{"/prk-00.data", TRAPDOORNAME} {
+		check(os.Rename(folder+name, staging+name))
+	}
+	if err := loaded.TryKeyGenLoad(8, large, "xyz", folder); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("Half moved folder reported as: %v", err)
+	}
+	check(acc.RecoverExtend(folder))
+	if err := loaded.TryKeyGenLoad(8, large, "xyz", folder); err != nil {
+		t.Errorf("Finished extension did not load: %s", err)
+	}
+
+	// TryExtend recovers first
+	check(os.Mkdir(staging, os.ModePerm))
+	check(os.Rename(folder+"/vrk-01.data", staging+"/vrk-01.data"))
+	if err := acc.TryExtend(8, large+1, folder, ""); err != nil {
+		t.Errorf("Extension after an interrupted one failed: %s", err)
+	}
+	if err := loaded.TryKeyGenLoad(8, large+1, "xyz", folder); err != nil {
+		t.Errorf("Extended folder did not load: %s", err)
+	}
+}
+
+// Extension of a ceremony: the new powers are only consistent once every participant has raised them.
+func TestExtendCeremony(t *testing.T) {
+
+	small, large := uint64(3), uint64(5)
+	folder := t.TempDir()
+	var coordinator BpAcc
+	coordinator.CeremonyInit(8, small, folder)
+
+	secrets := []string{t.TempDir() + "/contrib-0.secret", t.TempDir() + "/contrib-1.secret"}
+	for _, secret := range secrets {
+		var participant BpAcc
+		participant.KeyGenLoadPublic(8, small, folder)
+		if _, err := participant.ContributeExtendable(secret); err != nil {
+			t.Fatalf("Contribution failed: %s", err)
+		}
+	}
+
+	coordinator = BpAcc{}
+	coordinator.Extend(8, large, folder, "")
+	if err := coordinator.VerifyParams(); err == nil {
+		t.Errorf("Extended powers checked before the participants raised them")
+	}
+
+	for k, secret := range secrets {
+		var participant BpAcc
+		participant.KeyGenLoadPublic(8, large, folder)
+		if participant.VerifyCeremony(participant.LoadContributions()) {
+			t.Errorf("Ceremony checked with %d participants left", len(secrets)-k)
+		}
+		if err := participant.ExtendContribution(secret); err != nil {
+			t.Fatalf("Participant %d did not extend: %s", k, err)
+		}
+		if err := participant.ExtendContribution(secret); !errors.Is(err, ErrEllMismatch) {
+			t.Errorf("Second extension of participant %d reported as: %v", k, err)
+		}
+	}
+
+	var loaded BpAcc
+	if err := loaded.TryKeyGenLoadPublic(8, large, folder); err != nil {
+		t.Fatalf("Extended ceremony did not load: %s", err)
+	}
+	if !loaded.VerifyCeremony(loaded.LoadContributions()) {
+		t.Errorf("Extended ceremony did not check")
+	}
+
+	// A contribution to a prefix of the folder would leave the rest behind
+	var prefix BpAcc
+	prefix.KeyGenLoadPublic(8, small, folder)
+	if _, err := prefix.Contribute(); !errors.Is(err, ErrEllMismatch) {
+		t.Errorf("Contribution to a prefix reported as: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package bpacc
+
+import (
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Fast generation of the powers.
+// G^x, H^x and PedH^x use fixed-base windowed tables: the table of P holds d * 2^(8j) * P for every
+// byte j of the scalar and every digit d, thus P^x costs one (mixed) addition per byte and no doubling.
+// The results are brought to affine coordinates a chunk at a time, with a single inversion per chunk,
+// so that serializing them is cheap. The chunks are handed to Options.Workers goroutines, whatever the number of files.
+// Like G1Mul, none of this is constant time.
+
+const FIXED_WINDOW = 8
+const FIXED_WINDOWS = 32 // ceil(255 / FIXED_WINDOW)
+const FIXED_DIGITS = 1 << FIXED_WINDOW
+
+// Number of powers computed (and normalized) by a worker at once.
+const GEN_CHUNK = 1 << 10
+
+type fixedBaseG1 struct {
+	table [FIXED_WINDOWS][]mcl.G1 // table[j][d-1] = d * 2^(8j) * P
+}
+
+type fixedBaseG2 struct {
+	table [FIXED_WINDOWS][]mcl.G2
+}
+
+func newFixedBaseG1(P *mcl.G1) *fixedBaseG1 {
+	self := &fixedBaseG1{}
+	base := *P
+	for j := range self.table {
+		row := make([]mcl.G1, FIXED_DIGITS)
+		row[0] = base
+		for d := 1; d < FIXED_DIGITS; d++ {
+			mcl.G1Add(&row[d], &row[d-1], &base)
+		}
+		base = row[FIXED_DIGITS-1]
+		normalizeG1(row)
+		self.table[j] = row
+	}
+	return self
+}
+
+func newFixedBaseG2(P *mcl.G2) *fixedBaseG2 {
+	self := &fixedBaseG2{}
+	base := *P
+	for j := range self.table {
+		row := make([]mcl.G2, FIXED_DIGITS)
+		row[0] = base
+		for d := 1; d < FIXED_DIGITS; d++ {
+			mcl.G2Add(&row[d], &row[d-1], &base)
+		}
+		base = row[FIXED_DIGITS-1]
+		normalizeG2(row)
+		self.table[j] = row
+	}
+	return self
+}
+
+// The little-endian bytes of x, whatever the serialization mode of mcl (Serialize is big-endian in the ETH mode).
+func frDigits(x *mcl.Fr) [FIXED_WINDOWS]byte {
+	var be, digits [FIXED_WINDOWS]byte
+	str := x.GetString(16)
+	if len(str)%2 == 1 {
+		str = "0" + str
+	}
+	n, err := hex.Decode(be[FIXED_WINDOWS-len(str)/2:], []byte(str))
+	check(err)
+	for i := 0; i < n; i++ {
+		digits[i] = be[FIXED_WINDOWS-1-i]
+	}
+	return digits
+}
+
+func (self *fixedBaseG1) Mul(out *mcl.G1, x *mcl.Fr) {
+	out.Clear()
+	for j, digit := range frDigits(x) {
+		if digit != 0 {
+			mcl.G1Add(out, out, &self.table[j][digit-1])
+		}
+	}
+}
+
+func (self *fixedBaseG2) Mul(out *mcl.G2, x *mcl.Fr) {
+	out.Clear()
+	for j, digit := range frDigits(x) {
+		if digit != 0 {
+			mcl.G2Add(out, out, &self.table[j][digit-1])
+		}
+	}
+}
+
+// The batch normalization below writes the X, Y and Z of mcl's points directly, as mcl exports no batched form.
+// It depends on the representation of the pinned go-mcl (v0.0.0-20210224202455-eb6000c9b115): Jacobian coordinates
+// (x = X/Z^2, y = Y/Z^3), Z = 0 for the point at infinity, and Z = 1 for an affine point.
+// TestBatchNormalize checks all three against G1Normalize and G2Normalize, thus run it after upgrading mcl.
+// At run time the first is checked once as well, and G1Normalize and G2Normalize are used instead if it does not hold.
+var jacobianOnce sync.Once
+var jacobianG1, jacobianG2 bool
+
+func checkJacobian() {
+	jacobianOnce.Do(func() {
+		g, h := initG1G2()
+		var P, Q mcl.G1
+		mcl.G1Dbl(&P, &g)
+		mcl.G1Add(&P, &P, &g)
+		points := []mcl.G1{P}
+		batchNormalizeG1(points)
+		mcl.G1Normalize(&Q, &P)
+		jacobianG1 = points[0].X.IsEqual(&Q.X) && points[0].Y.IsEqual(&Q.Y) && points[0].Z.IsEqual(&Q.Z)
+
+		var R, S mcl.G2
+		mcl.G2Dbl(&R, &h)
+		mcl.G2Add(&R, &R, &h)
+		points2 := []mcl.G2{R}
+		batchNormalizeG2(points2)
+		mcl.G2Normalize(&S, &R)
+		jacobianG2 = points2[0].X.IsEqual(&S.X) && points2[0].Y.IsEqual(&S.Y) && points2[0].Z.IsEqual(&S.Z)
+	})
+}
+
+// Brings the points to affine coordinates (Z = 1).
+func normalizeG1(points []mcl.G1) {
+	checkJacobian()
+	if !jacobianG1 {
+		for i := range points {
+			mcl.G1Normalize(&points[i], &points[i])
+		}
+		return
+	}
+	batchNormalizeG1(points)
+}
+
+func normalizeG2(points []mcl.G2) {
+	checkJacobian()
+	if !jacobianG2 {
+		for i := range points {
+			mcl.G2Normalize(&points[i], &points[i])
+		}
+		return
+	}
+	batchNormalizeG2(points)
+}
+
+// Montgomery's trick: inverts all the Z at the cost of one inversion. Points at infinity (Z = 0) are left alone.
+func batchNormalizeG1(points []mcl.G1) {
+	prefix := make([]mcl.Fp, len(points))
+	var acc, inv, zInv, zInv2 mcl.Fp
+	acc.SetInt64(1)
+	for i := range points {
+		prefix[i] = acc
+		if !points[i].Z.IsZero() {
+			mcl.FpMul(&acc, &acc, &points[i].Z)
+		}
+	}
+	mcl.FpInv(&inv, &acc)
+	for i := len(points) - 1; i >= 0; i-- {
+		p := &points[i]
+		if p.Z.IsZero() {
+			continue
+		}
+		mcl.FpMul(&zInv, &inv, &prefix[i])
+		mcl.FpMul(&inv, &inv, &p.Z)
+		mcl.FpSqr(&zInv2, &zInv)
+		mcl.FpMul(&p.X, &p.X, &zInv2)
+		mcl.FpMul(&zInv2, &zInv2, &zInv)
+		mcl.FpMul(&p.Y, &p.Y, &zInv2)
+		p.Z.SetInt64(1)
+	}
+}
+
+func batchNormalizeG2(points []mcl.G2) {
+	prefix := make([]mcl.Fp2, len(points))
+	var acc, inv, zInv, zInv2 mcl.Fp2
+	acc.D[0].SetInt64(1)
+	for i := range points {
+		prefix[i] = acc
+		if !points[i].Z.IsZero() {
+			mcl.Fp2Mul(&acc, &acc, &points[i].Z)
+		}
+	}
+	mcl.Fp2Inv(&inv, &acc)
+	for i := len(points) - 1; i >= 0; i-- {
+		p := &points[i]
+		if p.Z.IsZero() {
+			continue
+		}
+		mcl.Fp2Mul(&zInv, &inv, &prefix[i])
+		mcl.Fp2Mul(&inv, &inv, &p.Z)
+		mcl.Fp2Sqr(&zInv2, &zInv)
+		mcl.Fp2Mul(&p.X, &p.X, &zInv2)
+		mcl.Fp2Mul(&zInv2, &zInv2, &zInv)
+		mcl.Fp2Mul(&p.Y, &p.Y, &zInv2)
+		p.Z.D[0].SetInt64(1)
+		p.Z.D[1].Clear()
+	}
+}
+
+// Computes the powers [start, stop) of every component in memory, from S, Alpha and the generators.
+// The segment files are written separately, see saveSegments.
+func (self *BpAcc) powersGen(start uint64, stop uint64) {
+
+	tableG := newFixedBaseG1(&self.G)
+	tableH := newFixedBaseG2(&self.H)
+	tablePedH := newFixedBaseG2(&self.PedH)
+
+	workers := self.workers()
+	next := start
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var a, b mcl.Fr
+			for {
+				lo := atomic.AddUint64(&next, GEN_CHUNK) - GEN_CHUNK
+				if lo >= stop {
+					break
+				}
+				hi := minUint64(lo+GEN_CHUNK, stop)
+
+				a = FrPow(self.S, int64(lo))
+				for i := lo; i < hi; i++ {
+					mcl.FrMul(&b, &a, &self.Alpha)
+					tableG.Mul(&self.PK[i], &a)
+					tableH.Mul(&self.VK[i], &a)
+					tableH.Mul(&self.VKAlpha[i], &b)
+					tablePedH.Mul(&self.PedVK[i], &a)
+					tablePedH.Mul(&self.PedVKAlpha[i], &b)
+					mcl.FrMul(&a, &a, &self.S)
+				}
+				normalizeG1(self.PK[lo:hi])
+				normalizeG2(self.VK[lo:hi])
+				normalizeG2(self.VKAlpha[lo:hi])
+				normalizeG2(self.PedVK[lo:hi])
+				normalizeG2(self.PedVKAlpha[lo:hi])
+			}
+			a.Clear()
+			b.Clear()
+		}()
+	}
+	wg.Wait()
+}
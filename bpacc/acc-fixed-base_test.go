@@ -0,0 +1,152 @@
+package bpacc
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Runs the baseline path PrkVrkParallel over NFILES segments, as KeyGen did before powersGen.
+func (self *BpAcc) prkVrkGenSegments() {
+	var wg sync.WaitGroup
+	num := self.Q + 1
+	step := uint64(math.Ceil(float64(num) / float64(NFILES)))
+	for i := uint8(0); i < NFILES; i++ {
+		start := minUint64(uint64(i)*step, num)
+		wg.Add(1)
+		go self.PrkVrkParallel(i, start, minUint64(start+step, num), &wg)
+	}
+	wg.Wait()
+}
+
+func TestPowersGen(t *testing.T) {
+
+	var x mcl.Fr
+	var P, Q mcl.G1
+	var R, S mcl.G2
+	g, h := initG1G2()
+	tableG, tableH := newFixedBaseG1(&g), newFixedBaseG2(&h)
+	for _, v := range []int64{0, 1, 255, 256, 65537} {
+		x.SetInt64(v)
+		tableG.Mul(&P, &x)
+		mcl.G1Mul(&Q, &g, &x)
+		tableH.Mul(&R, &x)
+		mcl.G2Mul(&S, &h, &x)
+		if !P.IsEqual(&Q) || !R.IsEqual(&S) {
+			t.Errorf("Fixed base multiplication by %d is wrong", v)
+		}
+	}
+
+	// The digits do not depend on the serialization mode
+	mcl.SetETHserialization(true)
+	x.Random()
+	tableG.Mul(&P, &x)
+	mcl.SetETHserialization(false)
+	mcl.G1Mul(&Q, &g, &x)
+	if !P.IsEqual(&Q) {
+		t.Errorf("Fixed base multiplication is wrong in the ETH mode")
+	}
+
+	points := make([]mcl.G1, 5)
+	for i := range points {
+		x.SetInt64(int64(i))
+		mcl.G1Mul(&points[i], &g, &x)
+		mcl.G1Dbl(&points[i], &points[i])
+	}
+	expected := append([]mcl.G1{}, points...)
+	normalizeG1(points)
+	for i := range points {
+		if !points[i].IsEqual(&expected[i]) {
+			t.Errorf("Normalization changed point %d", i)
+		}
+	}
+
+	// Same powers as the old path, with more workers than chunks and an odd number of workers
+	for _, workers := range []int{1, 3, 8} {
+		fast, slow := NewBpAcc(Options{Workers: workers}), NewBpAcc(Options{Workers: workers})
+		for _, acc := range []*BpAcc{fast, slow} {
+			acc.Init(11, "xyz", t.TempDir())
+			check(acc.trapdoorsGen())
+		}
+		fast.powersGen(0, fast.Q+1)
+		slow.prkVrkGenSegments()
+		for i := uint64(0); i <= fast.Q; i++ {
+			if !fast.PK[i].IsEqual(&slow.PK[i]) || !fast.VK[i].IsEqual(&slow.VK[i]) ||
+				!fast.VKAlpha[i].IsEqual(&slow.VKAlpha[i]) || !fast.PedVK[i].IsEqual(&slow.PedVK[i]) ||
+				!fast.PedVKAlpha[i].IsEqual(&slow.PedVKAlpha[i]) {
+				t.Fatalf("%d workers: power %d differs", workers, i)
+			}
+		}
+	}
+}
+
+// batchNormalizeG1 and batchNormalizeG2 against mcl's own normalization, see checkJacobian.
+func TestBatchNormalize(t *testing.T) {
+
+	checkJacobian()
+	if !jacobianG1 || !jacobianG2 {
+		t.Fatalf("mcl does not use the Jacobian coordinates batchNormalize expects: G1 %v, G2 %v", jacobianG1, jacobianG2)
+	}
+
+	g, h := initG1G2()
+	var x mcl.Fr
+	points := make([]mcl.G1, 6)
+	points2 := make([]mcl.G2, 6)
+	for i := range points {
+		x.Random()
+		mcl.G1Mul(&points[i], &g, &x)
+		mcl.G1Dbl(&points[i], &points[i])
+		mcl.G2Mul(&points2[i], &h, &x)
+		mcl.G2Dbl(&points2[i], &points2[i])
+	}
+	// The point at infinity and an affine point
+	points[1].Clear()
+	points2[1].Clear()
+	mcl.G1Normalize(&points[2], &points[2])
+	mcl.G2Normalize(&points2[2], &points2[2])
+
+	batch := append([]mcl.G1{}, points...)
+	batch2 := append([]mcl.G2{}, points2...)
+	batchNormalizeG1(batch)
+	batchNormalizeG2(batch2)
+	for i := range points {
+		var P mcl.G1
+		var Q mcl.G2
+		mcl.G1Normalize(&P, &points[i])
+		mcl.G2Normalize(&Q, &points2[i])
+		if !batch[i].X.IsEqual(&P.X) || !batch[i].Y.IsEqual(&P.Y) || !batch[i].Z.IsEqual(&P.Z) ||
+			!batch[i].IsEqual(&points[i]) {
+			t.Errorf("G1 point %d normalizes differently", i)
+		}
+		if !batch2[i].X.IsEqual(&Q.X) || !batch2[i].Y.IsEqual(&Q.Y) || !batch2[i].Z.IsEqual(&Q.Z) ||
+			!batch2[i].IsEqual(&points2[i]) {
+			t.Errorf("G2 point %d normalizes differently", i)
+		}
+	}
+	if !batch[1].IsZero() || !batch2[1].IsZero() {
+		t.Errorf("The point at infinity did not stay at infinity")
+	}
+}
+
+func BenchmarkPrkVrkGen(b *testing.B) {
+
+	for _, ell := range []uint64{10, 12} {
+		acc := NewBpAcc(Options{Workers: 8})
+		acc.Init(ell, "xyz", b.TempDir())
+		check(acc.trapdoorsGen())
+
+		b.Run(fmt.Sprintf("PrkVrkParallel;2^%d", ell), func(b *testing.B) {
+			for j := 0; j < b.N; j++ {
+				acc.prkVrkGenSegments()
+			}
+		})
+		b.Run(fmt.Sprintf("FixedBase;2^%d", ell), func(b *testing.B) {
+			for j := 0; j < b.N; j++ {
+				check(acc.prkVrkGen())
+			}
+		})
+	}
+}
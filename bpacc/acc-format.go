@@ -0,0 +1,481 @@
+package bpacc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alinush/go-mcl"
+	"golang.org/x/crypto/blake2b"
+)
+
+// On-disk format of the parameter folder.
+// Every file (trapdoors.data, public.data, the segment files and the ceremony records) starts with a FileHeader,
+// followed by the raw points in the encoding recorded in the header (see acc-encoding.go).
+// The folder carries a manifest.json listing every file along with its element range, size and blake2b-256 hash.
+// The ELL of the setup is the one of the manifest, trapdoors.data and public.data; the other files keep the ELL
+// they were written at, thus a setup extended with TryExtend does not rewrite its old segments.
+// KeyGenLoad checks the manifest and the headers before populating BpAcc.
+
+const FORMAT_MAGIC = "BPAC"
+const FORMAT_VERSION = 1
+const MANIFESTNAME = "/manifest.json"
+
+// Components of the files that are not segments
+const TRAPDOOR_COMPONENT = "trapdoors"
+const PUBLIC_COMPONENT = "public"
+const SECRET_COMPONENT = "secret"
+const CONTRIBUTION_COMPONENT = "contrib"
+const CONTRIBUTION_SECRET_COMPONENT = "contrib-secret"
+
+// Segment file name -> component name, e.g. "/prk-%02d.data" -> "prk"
+var SEGMENT_NAMES = []string{PRK_NAME, VRK_NAME, VRK_KEA_NAME, PED_VRK_NAME, PED_VRK_KEA_NAME}
+
+type FileHeader struct {
+	Magic     [4]byte
+	Version   uint16
+	Curve     uint16
+	Encoding  uint8
+	Component [16]byte
+	ELL       uint64
+	Segment   uint32
+	Start     uint64 // First element of the file
+	Stop      uint64 // One past the last element. Start = Stop = 0 for files that are not segments, but the verifier bundle.
+}
+
+var HEADER_SIZE = binary.Size(FileHeader{})
+
+type Manifest struct {
+	Version uint16          `json:"version"`
+	Curve   uint16          `json:"curve"`
+	ELL     uint64          `json:"ell"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+type ManifestEntry struct {
+	Name      string `json:"name"`
+	Component string `json:"component"`
+	Segment   uint32 `json:"segment"`
+	Start     uint64 `json:"start"`
+	Stop      uint64 `json:"stop"`
+	Size      int64  `json:"size"`
+	Hash      string `json:"blake2b"`
+}
+
+func componentName(pattern string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "-%02d.data")
+}
+
+// The header of a file of self, in its Options.Encoding.
+func (self *BpAcc) newFileHeader(component string, segment uint32, start uint64, stop uint64) FileHeader {
+	header := NewFileHeader(component, self.ELL, segment, start, stop)
+	header.Encoding = uint8(self.opts.Encoding)
+	return header
+}
+
+// A header in the compressed encoding, see BpAcc.newFileHeader for the files of an accumulator.
+func NewFileHeader(component string, ell uint64, segment uint32, start uint64, stop uint64) FileHeader {
+	var header FileHeader
+	copy(header.Magic[:], FORMAT_MAGIC)
+	header.Version = FORMAT_VERSION
+	header.Curve = mcl.BLS12_381
+	header.Encoding = uint8(POINT_COMPRESSED)
+	copy(header.Component[:], component)
+	header.ELL = ell
+	header.Segment = segment
+	header.Start = start
+	header.Stop = stop
+	return header
+}
+
+func (self *FileHeader) Name() string {
+	return strings.TrimRight(string(self.Component[:]), "\x00")
+}
+
+func WriteHeader(w io.Writer, header FileHeader) error {
+	return binary.Write(w, binary.LittleEndian, &header)
+}
+
+// Reads a header and checks that this build can read the rest of the file.
+func ReadHeader(r io.Reader) (FileHeader, error) {
+	var header FileHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrShortRead
+		}
+		return header, err
+	}
+	if string(header.Magic[:]) != FORMAT_MAGIC {
+		return header, fmt.Errorf("%w: not a parameter file (magic %q)", ErrFormat, header.Magic[:])
+	}
+	if header.Version != FORMAT_VERSION {
+		return header, fmt.Errorf("%w: unsupported format version %d, wants %d", ErrFormat, header.Version, FORMAT_VERSION)
+	}
+	if header.Curve != mcl.BLS12_381 {
+		return header, fmt.Errorf("%w: unsupported curve %d, wants %d", ErrFormat, header.Curve, mcl.BLS12_381)
+	}
+	if !PointEncoding(header.Encoding).Valid() {
+		return header, fmt.Errorf("%w: unsupported point encoding %d", ErrFormat, header.Encoding)
+	}
+	return header, nil
+}
+
+// Checks that the header describes the expected file.
+func (self *FileHeader) Expect(component string, segment uint32) error {
+	if self.Name() != component {
+		return fmt.Errorf("%w: component %s, wants %s", ErrFormat, self.Name(), component)
+	}
+	if self.Segment != segment {
+		return fmt.Errorf("%w: segment %d, wants %d", ErrFormat, self.Segment, segment)
+	}
+	if self.Start > self.Stop {
+		return fmt.Errorf("%w: empty range [%d, %d)", ErrFormat, self.Start, self.Stop)
+	}
+	return nil
+}
+
+func hashFile(fileName string) (string, int64, error) {
+	f, err := openFile(fileName)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	hash, err := blake2b.New256(nil)
+	if err != nil {
+		return "", 0, err
+	}
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, &FileError{fileName, err}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+func fileSize(fileName string) (int64, error) {
+	f, err := openFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, &FileError{fileName, err}
+	}
+	return info.Size(), nil
+}
+
+// Files of the folder that belong to the manifest, in a fixed order.
+func (self *BpAcc) parameterFiles() ([]string, error) {
+	return parameterFilesIn(self.folderPath)
+}
+
+// Same as parameterFiles, over several folders: a file of a later folder replaces the file of the same name
+// of an earlier one.
+func parameterFilesIn(folders ...string) ([]string, error) {
+	patterns := []string{TRAPDOORNAME[1:], PUBLICNAME[1:]}
+	for _, pattern := range SEGMENT_NAMES {
+		patterns = append(patterns, componentName(pattern)+"-[0-9][0-9].data")
+	}
+	patterns = append(patterns, "contrib-[0-9][0-9][0-9].data")
+
+	files := make([]string, 0)
+	for _, pattern := range patterns {
+		byName := make(map[string]string)
+		for _, folder := range folders {
+			matches, err := filepath.Glob(folder + "/" + pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, path := range matches {
+				byName[filepath.Base(path)] = path
+			}
+		}
+		names := make([]string, 0, len(byName))
+		for name := range byName {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, byName[name])
+		}
+	}
+	return files, nil
+}
+
+// Reads the header of a file on its own.
+func readFileHeader(path string) (FileHeader, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return FileHeader{}, err
+	}
+	defer f.Close()
+	header, err := ReadHeader(f)
+	if err != nil {
+		return header, &FileError{path, err}
+	}
+	return header, nil
+}
+
+// Writes manifest.json for the files currently in the folder.
+// Has to be called once all the files are written.
+func (self *BpAcc) SaveManifest() {
+	check(self.saveManifest())
+}
+
+func (self *BpAcc) saveManifest() error {
+	files, err := self.parameterFiles()
+	if err != nil {
+		return err
+	}
+	return self.writeManifest(files, nil)
+}
+
+// Writes manifest.json of the folder for files, which may live elsewhere (see TryExtend); only their names are recorded.
+// The entry of a file found in known is taken as is, the other files are hashed.
+func (self *BpAcc) writeManifest(files []string, known map[string]ManifestEntry) error {
+
+	fileName := self.folderPath + MANIFESTNAME
+	self.logln("Saving data to:", fileName)
+
+	manifest := Manifest{Version: FORMAT_VERSION, Curve: mcl.BLS12_381, ELL: self.ELL}
+	for _, path := range files {
+		if entry, ok := known[path]; ok {
+			manifest.Files = append(manifest.Files, entry)
+			continue
+		}
+		header, err := readFileHeader(path)
+		if err != nil {
+			return err
+		}
+
+		hash, size, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Name:      filepath.Base(path),
+			Component: header.Name(),
+			Segment:   header.Segment,
+			Start:     header.Start,
+			Stop:      header.Stop,
+			Size:      size,
+			Hash:      hash,
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return &FileError{fileName, err}
+	}
+	return nil
+}
+
+func (self *BpAcc) LoadManifest() (Manifest, error) {
+	var manifest Manifest
+	fileName := self.folderPath + MANIFESTNAME
+	f, err := openFile(fileName)
+	if err != nil {
+		return manifest, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return manifest, &FileError{fileName, fmt.Errorf("%w: %v", ErrFormat, err)}
+	}
+	return manifest, nil
+}
+
+// Checks the folder against its manifest: every file is present with the recorded size and hash,
+// the headers agree with the manifest, no unlisted segment is lying around,
+// and the segments of every component cover exactly the 2^ELL+1 powers.
+func (self *BpAcc) VerifyManifest() error {
+	return self.verifyManifest(true)
+}
+
+// Same as VerifyManifest, but the files are only hashed when hashes is set; otherwise only their sizes are compared.
+func (self *BpAcc) verifyManifest(hashes bool) error {
+
+	manifest, err := self.loadListedManifest()
+	if err != nil {
+		return err
+	}
+	for _, entry := range manifest.Files {
+		if err := self.verifyManifestEntry(manifest, entry, hashes); err != nil {
+			return err
+		}
+	}
+
+	num := uint64(1)<<manifest.ELL + 1
+	covered, err := self.coveredElements(manifest.Files)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range SEGMENT_NAMES {
+		component := componentName(pattern)
+		if covered[component] != num {
+			return &FileError{self.folderPath + MANIFESTNAME, fmt.Errorf("%w: %s covers %d elements, wants %d", ErrMissingFile, component, covered[component], num)}
+		}
+	}
+	return nil
+}
+
+// Same as VerifyManifest, restricted to what loading the first 2^L+1 powers reads:
+// the file of the given component (trapdoors or public) and the segments starting below 2^L+1.
+// The other files are only required to be listed, so a small prefix of a large setup is checked quickly.
+func (self *BpAcc) verifyManifestPrefix(L uint64, component string) error {
+
+	manifest, err := self.loadListedManifest()
+	if err != nil {
+		return err
+	}
+	if manifest.ELL < L {
+		return &FileError{self.folderPath + MANIFESTNAME, fmt.Errorf("%w: There is not enough to read! Found: %d, Wants: %d", ErrEllMismatch, manifest.ELL, L)}
+	}
+
+	num := uint64(1)<<L + 1
+	needed := make([]ManifestEntry, 0)
+	found := false
+	for _, entry := range manifest.Files {
+		if entry.Component == component {
+			found = true
+		} else if !isSegmentComponent(entry.Component) || entry.Start >= num {
+			continue
+		}
+		if err := self.verifyManifestEntry(manifest, entry, true); err != nil {
+			return err
+		}
+		needed = append(needed, entry)
+	}
+	if !found {
+		return &FileError{self.folderPath + MANIFESTNAME, fmt.Errorf("%w: %s is not listed", ErrMissingFile, component)}
+	}
+
+	covered, err := self.coveredElements(needed)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range SEGMENT_NAMES {
+		component := componentName(pattern)
+		if covered[component] < num {
+			return &FileError{self.folderPath + MANIFESTNAME, fmt.Errorf("%w: %s covers %d elements, wants %d", ErrMissingFile, component, covered[component], num)}
+		}
+	}
+	return nil
+}
+
+// Loads the manifest and checks that it lists every parameter file of the folder.
+func (self *BpAcc) loadListedManifest() (Manifest, error) {
+
+	fileName := self.folderPath + MANIFESTNAME
+	manifest, err := self.LoadManifest()
+	if errors.Is(err, ErrMissingFile) && self.isLegacyFolder() {
+		return manifest, &FileError{self.folderPath, fmt.Errorf("%w: written before the file headers, run Migrate first", ErrFormat)}
+	}
+	if err != nil {
+		return manifest, err
+	}
+	if manifest.Version != FORMAT_VERSION || manifest.Curve != mcl.BLS12_381 {
+		return manifest, &FileError{fileName, fmt.Errorf("%w: unsupported version %d or curve %d", ErrFormat, manifest.Version, manifest.Curve)}
+	}
+
+	listed := make(map[string]bool)
+	for _, entry := range manifest.Files {
+		listed[self.folderPath+"/"+entry.Name] = true
+	}
+	files, err := self.parameterFiles()
+	if err != nil {
+		return manifest, err
+	}
+	for _, path := range files {
+		if !listed[path] {
+			return manifest, &FileError{path, fmt.Errorf("%w: not listed in the manifest", ErrFormat)}
+		}
+	}
+	return manifest, nil
+}
+
+// Checks one file against its manifest entry, hashing it when hashes is set.
+func (self *BpAcc) verifyManifestEntry(manifest Manifest, entry ManifestEntry, hashes bool) error {
+
+	path := self.folderPath + "/" + entry.Name
+	var hash string
+	var size int64
+	var err error
+	if hashes {
+		hash, size, err = hashFile(path)
+	} else {
+		hash = entry.Hash
+		size, err = fileSize(path)
+	}
+	if err != nil {
+		return err
+	}
+	if size < entry.Size {
+		return &FileError{path, fmt.Errorf("%w: %d bytes, wants %d", ErrShortRead, size, entry.Size)}
+	}
+	if size != entry.Size || hash != entry.Hash {
+		return &FileError{path, fmt.Errorf("%w: does not match its size or hash in the manifest", ErrFormat)}
+	}
+
+	header, err := readFileHeader(path)
+	if err != nil {
+		return err
+	}
+	if err := header.Expect(entry.Component, entry.Segment); err != nil {
+		return &FileError{path, err}
+	}
+	// The segments and the contribution records keep the ELL they were written at, see TryExtend
+	rewritten := entry.Component == TRAPDOOR_COMPONENT || entry.Component == PUBLIC_COMPONENT
+	if header.ELL > manifest.ELL || (rewritten && header.ELL != manifest.ELL) {
+		return &FileError{path, fmt.Errorf("%w: ELL %d, wants %d", ErrEllMismatch, header.ELL, manifest.ELL)}
+	}
+	if header.Start != entry.Start || header.Stop != entry.Stop {
+		return &FileError{path, fmt.Errorf("%w: holds [%d, %d), wants [%d, %d)", ErrFormat, header.Start, header.Stop, entry.Start, entry.Stop)}
+	}
+	return nil
+}
+
+// Checks that the segment entries of every component follow each other from 0,
+// and returns the number of elements each component covers.
+func (self *BpAcc) coveredElements(entries []ManifestEntry) (map[string]uint64, error) {
+
+	ranges := make(map[string][]ManifestEntry)
+	for _, entry := range entries {
+		ranges[entry.Component] = append(ranges[entry.Component], entry)
+	}
+
+	covered := make(map[string]uint64)
+	for _, pattern := range SEGMENT_NAMES {
+		component := componentName(pattern)
+		entries := ranges[component]
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Start < entries[j].Start })
+		next := uint64(0)
+		for _, entry := range entries {
+			if entry.Start != next {
+				return nil, &FileError{self.folderPath + MANIFESTNAME, fmt.Errorf("%w: %s is missing [%d, %d)", ErrMissingFile, component, next, entry.Start)}
+			}
+			next = entry.Stop
+		}
+		covered[component] = next
+	}
+	return covered, nil
+}
+
+func isSegmentComponent(component string) bool {
+	for _, pattern := range SEGMENT_NAMES {
+		if componentName(pattern) == component {
+			return true
+		}
+	}
+	return false
+}
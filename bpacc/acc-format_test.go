@@ -0,0 +1,126 @@
+package bpacc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestFileHeader(t *testing.T) {
+
+	var buf bytes.Buffer
+	check(WriteHeader(&buf, NewFileHeader("vrk-kea", 12, 3, 100, 200)))
+	if buf.Len() != HEADER_SIZE {
+		t.Fatalf("Header has %d bytes, wants %d", buf.Len(), HEADER_SIZE)
+	}
+
+	header, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Name() != "vrk-kea" || header.ELL != 12 || header.Segment != 3 || header.Start != 100 || header.Stop != 200 {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+	if header.Expect("vrk-kea", 3) != nil || header.Expect("vrk", 3) == nil || header.Expect("vrk-kea", 4) == nil {
+		t.Errorf("Expect does not tell the files apart")
+	}
+
+	// A raw file from before the header was introduced
+	var g BpAcc
+	g.Init(1, "", "")
+	g.GeneratorsGen()
+	if _, err := ReadHeader(bytes.NewReader(append(g.G.Serialize(), g.H.Serialize()...))); err == nil {
+		t.Errorf("Headerless data accepted")
+	}
+}
+
+func TestManifest(t *testing.T) {
+
+	l := uint64(5)
+	folder := t.TempDir()
+	var acc BpAcc
+	acc.KeyGenPublic(8, l, folder, "")
+
+	if err := acc.VerifyManifest(); err != nil {
+		t.Fatalf("Fresh folder did not verify: %s", err)
+	}
+
+	segment := folder + fmt.Sprintf(VRK_NAME, 1)
+	original, err := os.ReadFile(segment)
+	check(err)
+
+	tampered := append([]byte{}, original...)
+	tampered[len(tampered)-1] ^= 1
+	corruptions := map[string][]byte{
+		"truncated": original[:len(original)-acc.GetG2ByteSize()],
+		"flipped":   tampered,
+	}
+	for name, data := range corruptions {
+		check(os.WriteFile(segment, data, 0644))
+		if err := acc.VerifyManifest(); err == nil {
+			t.Errorf("%s segment accepted", name)
+		}
+	}
+
+	check(os.Remove(segment))
+	if err := acc.VerifyManifest(); err == nil {
+		t.Errorf("Missing segment accepted")
+	}
+	check(os.WriteFile(segment, original, 0644))
+
+	extra := folder + fmt.Sprintf(PRK_NAME, 42)
+	check(os.WriteFile(extra, original, 0644))
+	if err := acc.VerifyManifest(); err == nil {
+		t.Errorf("Unlisted segment accepted")
+	}
+	check(os.Remove(extra))
+
+	if err := acc.VerifyManifest(); err != nil {
+		t.Errorf("Restored folder did not verify: %s", err)
+	}
+
+	// KeyGenLoad refuses to populate from a corrupted folder
+	check(os.WriteFile(segment, tampered, 0644))
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Corrupted folder loaded")
+		}
+	}()
+	var loaded BpAcc
+	loaded.KeyGenLoadPublic(8, l, folder)
+}
+
+// Loading a prefix hashes only the segments it reads
+func TestManifestPrefix(t *testing.T) {
+
+	l := uint64(4)
+	folder := t.TempDir()
+	check(NewBpAcc(Options{Segments: 4}).TryKeyGenPublic(8, l, folder, ""))
+
+	// The last segment holds [15, 17), which the prefix of ELL 2 does not need
+	segment := folder + fmt.Sprintf(VRK_NAME, 3)
+	data, err := os.ReadFile(segment)
+	check(err)
+	data[len(data)-1] ^= 1
+	check(os.WriteFile(segment, data, 0644))
+
+	var loaded BpAcc
+	if err := loaded.TryKeyGenLoadPublic(8, 2, folder); err != nil {
+		t.Errorf("Prefix did not load: %s", err)
+	}
+	if err := loaded.TryKeyGenLoadPublic(8, l, folder); !errors.Is(err, ErrFormat) {
+		t.Errorf("Corrupted segment loaded, or unexpected error: %v", err)
+	}
+
+	// A needed segment is hashed
+	segment = folder + fmt.Sprintf(PRK_NAME, 0)
+	data, err = os.ReadFile(segment)
+	check(err)
+	data[len(data)-1] ^= 1
+	check(os.WriteFile(segment, data, 0644))
+	if err := loaded.TryKeyGenLoadPublic(8, 2, folder); !errors.Is(err, ErrFormat) {
+		t.Errorf("Corrupted prefix loaded, or unexpected error: %v", err)
+	}
+}
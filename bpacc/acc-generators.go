@@ -0,0 +1,223 @@
+package bpacc
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Nothing-up-my-sleeve generators.
+// G, H, PedH, A[i] and B[i] are hashed to the curve from a public label, so nobody knows
+// a discrete log relation among them. The procedure (also described in the Readme) is:
+//
+//	msg   = name = "G" (G1), "H" (G2), "PedH" (G2), "A/<i>" (G1) and "B/<i>" (G2) for i = 0 .. SPARE-1
+//	DST   = label || "-with-" || suite
+//	point = hash_to_curve(msg, DST) (RFC 9380, SSWU, random oracle)
+//
+// with the suites BLS12381G1_XMD:SHA-256_SSWU_RO_ and BLS12381G2_XMD:SHA-256_SSWU_RO_, thus with GENERATORS_LABEL
+// the DSTs are GENERATORS_DST_G1 and GENERATORS_DST_G2. hash_to_field is computed here, map_to_curve and
+// clear_cofactor by MapToG1 and MapToG2 of mcl in the IRTF mode.
+const GENERATORS_LABEL = "BPACC-V1-BLS12381-GENERATORS"
+
+const GENERATORS_SUITE_G1 = "BLS12381G1_XMD:SHA-256_SSWU_RO_"
+const GENERATORS_SUITE_G2 = "BLS12381G2_XMD:SHA-256_SSWU_RO_"
+
+const GENERATORS_DST_G1 = GENERATORS_LABEL + "-with-" + GENERATORS_SUITE_G1
+const GENERATORS_DST_G2 = GENERATORS_LABEL + "-with-" + GENERATORS_SUITE_G2
+
+// Sets the map-to-curve mode of mcl that the generators need. Init calls it.
+// The mode is a global of mcl, thus any other HashAndMapTo or MapToG1/G2 of the process (including other packages)
+// uses it from then on, and a later mcl.SetMapToMode elsewhere would change the generators derived here:
+// checkMapToMode catches that before every derivation.
+func SetMapToMode() error {
+	return mcl.SetMapToMode(mcl.IRTF)
+}
+
+// map_to_curve followed by clear_cofactor of 1, in G1 and G2 (x only), as in RFC 9380.
+const MAP_TO_G1_ONE_X = "1073311196f8ef19477219ccee3a48035ff432295aa9419eed45d186027d88b90832e14c4f0e2aa4d15f54d1c3ed0f93"
+const MAP_TO_G2_ONE_X0 = "1770d4f641225e1a1c0f7d05857299763e98e47ec6355b81dd6cdaf6db6825052f71d35ede3af8b70f046474c48d712e"
+const MAP_TO_G2_ONE_X1 = "e12b55d801607d9760f8637ac80a4fececd3eb74045b342ee3c7dddd2037e72dedccc27e9a89491d4e57bde555fead"
+
+// mcl has no getter for the map-to-curve mode, thus it is checked on the map of 1.
+func checkMapToMode() error {
+	var u mcl.Fp
+	var u2 mcl.Fp2
+	var P mcl.G1
+	var Q mcl.G2
+	u.SetInt64(1)
+	u2.D[0].SetInt64(1)
+	if err := mcl.MapToG1(&P, &u); err != nil {
+		return err
+	}
+	if err := mcl.MapToG2(&Q, &u2); err != nil {
+		return err
+	}
+	mcl.G1Normalize(&P, &P)
+	mcl.G2Normalize(&Q, &Q)
+	if P.X.GetString(16) != MAP_TO_G1_ONE_X || Q.X.D[0].GetString(16) != MAP_TO_G2_ONE_X0 || Q.X.D[1].GetString(16) != MAP_TO_G2_ONE_X1 {
+		return errors.New("mcl is not in the map-to-curve mode of the generators, call SetMapToMode")
+	}
+	return nil
+}
+
+// expand_message_xmd with SHA-256 (RFC 9380, section 5.3.1).
+func expandMessageXMD(msg []byte, dst string, n int) ([]byte, error) {
+	ell := (n + sha256.Size - 1) / sha256.Size
+	if len(dst) > 255 || ell > 255 || n > 65535 {
+		return nil, fmt.Errorf("expand_message_xmd: DST of %d bytes, %d bytes requested", len(dst), n)
+	}
+	dstPrime := append([]byte(dst), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, h.BlockSize()))
+	h.Write(msg)
+	h.Write([]byte{byte(n >> 8), byte(n), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	out := make([]byte, 0, ell*sha256.Size)
+	bi := make([]byte, sha256.Size)
+	for i := 1; i <= ell; i++ {
+		for j := range bi {
+			bi[j] ^= b0[j]
+		}
+		h.Reset()
+		h.Write(bi)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:n], nil
+}
+
+// hash_to_field of the suites above: count elements of Fp, from 64 bytes each.
+func hashToField(msg []byte, dst string, count int) ([]mcl.Fp, error) {
+	buf, err := expandMessageXMD(msg, dst, 64*count)
+	if err != nil {
+		return nil, err
+	}
+	p, _ := new(big.Int).SetString(mcl.GetFieldOrder(), 10)
+	u := make([]mcl.Fp, count)
+	for i := range u {
+		v := new(big.Int).SetBytes(buf[64*i : 64*(i+1)])
+		if err := u[i].SetString(v.Mod(v, p).Text(16), 16); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+func HashToG1(label string, name string) mcl.G1 {
+	check(checkMapToMode())
+	u, err := hashToField([]byte(name), label+"-with-"+GENERATORS_SUITE_G1, 2)
+	check(err)
+	var P, P1 mcl.G1
+	check(mcl.MapToG1(&P, &u[0]))
+	check(mcl.MapToG1(&P1, &u[1]))
+	mcl.G1Add(&P, &P, &P1)
+	return P
+}
+
+func HashToG2(label string, name string) mcl.G2 {
+	check(checkMapToMode())
+	u, err := hashToField([]byte(name), label+"-with-"+GENERATORS_SUITE_G2, 4)
+	check(err)
+	var u0, u1 mcl.Fp2
+	u0.D[0], u0.D[1] = u[0], u[1]
+	u1.D[0], u1.D[1] = u[2], u[3]
+	var P, P1 mcl.G2
+	check(mcl.MapToG2(&P, &u0))
+	check(mcl.MapToG2(&P1, &u1))
+	mcl.G2Add(&P, &P, &P1)
+	return P
+}
+
+// Derives the extra generators A[i] and B[i].
+func DeriveSpareGenerators(label string) ([]mcl.G1, []mcl.G2) {
+	A := make([]mcl.G1, SPARE)
+	B := make([]mcl.G2, SPARE)
+	for i := range A {
+		A[i] = HashToG1(label, fmt.Sprintf("A/%d", i))
+		B[i] = HashToG2(label, fmt.Sprintf("B/%d", i))
+	}
+	return A, B
+}
+
+// Re-derives all the generators from label and compares them with the loaded parameters.
+// Parameters imported from an external transcript keep the transcript's G and H and a local PedH,
+// thus they fail here; check them with CheckSpareGenerators instead.
+func (self *BpAcc) CheckGenerators(label string) error {
+
+	G := HashToG1(label, "G")
+	H := HashToG2(label, "H")
+	PedH := HashToG2(label, "PedH")
+
+	pk0, vk0, pedVK0 := self.G, self.H, self.PedH
+	// The first powers, unless none are held (a BpVerifier only holds the last ones of PK)
+	if self.store != nil || (len(self.PK) > 0 && self.pkStart == 0) {
+		var err error
+		if pk0, err = self.PKAt(0); err != nil {
+			return err
+		}
+		if vk0, err = self.VKAt(0); err != nil {
+			return err
+		}
+		if pedVK0, err = self.PedVKAt(0); err != nil {
+			return err
+		}
+	}
+	if !self.G.IsEqual(&G) || !pk0.IsEqual(&G) {
+		return &ParamsError{"G", 0, "does not match the hash-to-curve derivation"}
+	}
+	if !self.H.IsEqual(&H) || !vk0.IsEqual(&H) {
+		return &ParamsError{"H", 0, "does not match the hash-to-curve derivation"}
+	}
+	if !self.PedH.IsEqual(&PedH) || !pedVK0.IsEqual(&PedH) {
+		return &ParamsError{"PedH", 0, "does not match the hash-to-curve derivation"}
+	}
+	if err := self.CheckSpareGenerators(label); err != nil {
+		return err
+	}
+
+	// Values derived from G and H
+	var gneg mcl.G1
+	var hneg mcl.G2
+	var idGT, invIdGT mcl.GT
+	mcl.G1Neg(&gneg, &G)
+	mcl.G2Neg(&hneg, &H)
+	mcl.Pairing(&idGT, &G, &H)
+	mcl.GTInv(&invIdGT, &idGT)
+	if !self.Gneg.IsEqual(&gneg) {
+		return &ParamsError{"Gneg", 0, "is not the inverse of G"}
+	}
+	if !self.Hneg.IsEqual(&hneg) {
+		return &ParamsError{"Hneg", 0, "is not the inverse of H"}
+	}
+	if !self.IdGT.IsEqual(&idGT) || !self.InvIdGT.IsEqual(&invIdGT) {
+		return &ParamsError{"IdGT", 0, "is not e(G, H)"}
+	}
+	return nil
+}
+
+// Re-derives A and B from label and compares them with the loaded parameters.
+// This is the part of CheckGenerators that also holds for parameters imported with ImportPtau or ImportKZGJSON.
+func (self *BpAcc) CheckSpareGenerators(label string) error {
+
+	A, B := DeriveSpareGenerators(label)
+	if len(self.A) != len(A) || len(self.B) != len(B) {
+		return &ParamsError{"A", 0, fmt.Sprintf("expected %d spare generators", SPARE)}
+	}
+	for i := range A {
+		if !self.A[i].IsEqual(&A[i]) {
+			return &ParamsError{"A", uint64(i), "does not match the hash-to-curve derivation"}
+		}
+		if !self.B[i].IsEqual(&B[i]) {
+			return &ParamsError{"B", uint64(i), "does not match the hash-to-curve derivation"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package bpacc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestCheckGenerators(t *testing.T) {
+
+	l := uint64(5)
+	var acc BpAcc
+	acc.KeyGenPublic(8, l, t.TempDir(), "")
+
+	if err := acc.CheckGenerators(GENERATORS_LABEL); err != nil {
+		t.Fatalf("Derived generators did not check: %s", err)
+	}
+	if err := acc.CheckGenerators("some-other-label"); err == nil {
+		t.Errorf("Generators checked under a different label")
+	}
+
+	// The derivation is deterministic and the generators are distinct
+	A, B := DeriveSpareGenerators(GENERATORS_LABEL)
+	G := HashToG1(GENERATORS_LABEL, "G")
+	if !G.IsEqual(&acc.G) || G.IsEqual(&A[0]) || A[0].IsEqual(&A[1]) || B[0].IsEqual(&B[1]) {
+		t.Errorf("Unexpected derivation")
+	}
+
+	original := acc.A[2]
+	acc.A[2] = acc.A[1]
+	err := acc.CheckGenerators(GENERATORS_LABEL)
+	acc.A[2] = original
+
+	perr, ok := err.(*ParamsError)
+	if !ok || perr.Component != "A" || perr.Index != 2 {
+		t.Errorf("Tampered A[2] reported as: %v", err)
+	}
+
+	original2 := acc.PedH
+	acc.PedH = acc.H
+	err = acc.CheckGenerators(GENERATORS_LABEL)
+	acc.PedH = original2
+
+	perr, ok = err.(*ParamsError)
+	if !ok || perr.Component != "PedH" {
+		t.Errorf("Tampered PedH reported as: %v", err)
+	}
+}
+
+// Test vectors of RFC 9380 (appendices K.1 and J.9.1).
+func TestHashToField(t *testing.T) {
+	out, err := expandMessageXMD([]byte(""), "QUUX-V01-CS02-with-expander-SHA256-128", 32)
+	if err != nil || hex.EncodeToString(out) != "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235" {
+		t.Errorf("Unexpected expand_message_xmd: %x %v", out, err)
+	}
+
+	u, err := hashToField([]byte(""), "QUUX-V01-CS02-with-"+GENERATORS_SUITE_G1, 2)
+	if err != nil {
+		t.Fatalf("hash_to_field failed: %s", err)
+	}
+	if u[0].GetString(16) != "ba14bd907ad64a016293ee7c2d276b8eae71f25a4b941eece7b0d89f17f75cb3ae5438a614fb61d6835ad59f29c564f" ||
+		u[1].GetString(16) != "19b9bd7979f12657976de2884c7cce192b82c177c80e0ec604436a7f538d231552f0d96d9f7babe5fa3b19b3ff25ac9" {
+		t.Errorf("Unexpected hash_to_field: %s %s", u[0].GetString(16), u[1].GetString(16))
+	}
+}
+
+// Another map-to-curve mode of mcl is caught before deriving.
+func TestMapToMode(t *testing.T) {
+	if err := SetMapToMode(); err != nil {
+		t.Fatalf("SetMapToMode failed: %s", err)
+	}
+	if err := checkMapToMode(); err != nil {
+		t.Fatalf("IRTF mode reported as: %s", err)
+	}
+	G := HashToG1(GENERATORS_LABEL, "G")
+
+	check(mcl.SetMapToMode(0))
+	defer func() { check(SetMapToMode()) }()
+	if err := checkMapToMode(); err == nil {
+		t.Errorf("Another mode was not caught")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Derived the generators in another mode")
+			}
+		}()
+		HashToG1(GENERATORS_LABEL, "G")
+	}()
+
+	// Init sets it back
+	var acc BpAcc
+	acc.Init(1, "", "")
+	if P := HashToG1(GENERATORS_LABEL, "G"); !P.IsEqual(&G) {
+		t.Errorf("Generators differ after Init")
+	}
+}
@@ -0,0 +1,490 @@
+package bpacc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Importers for externally generated powers of tau.
+// PK and VK are taken from the transcript. VKAlpha, PedVK and PedVKAlpha cannot be derived from
+// the transcript alone, thus they come from a fresh local contribution (alpha and the Pedersen generator)
+// which is wiped right after. The ImportReport spells out which component is trusted to whom.
+
+const PTAU_MAGIC = "ptau"
+const PTAU_SECTION_HEADER = 1
+const PTAU_SECTION_TAU_G1 = 2
+const PTAU_SECTION_TAU_G2 = 3
+
+type ImportReport struct {
+	Source   string
+	Format   string
+	MaxELL   uint64   // Largest ELL the transcript can support
+	ELL      uint64   // ELL that was imported
+	External []string // Components taken from the transcript
+	Local    []string // Components derived from the local contribution
+	Derived  []string // Components hashed to the curve from GENERATORS_LABEL
+}
+
+func (self ImportReport) String() string {
+	out := fmt.Sprintf("Imported %s transcript %s: ELL %d (transcript supports up to %d)\n", self.Format, self.Source, self.ELL, self.MaxELL)
+	out += fmt.Sprintf("  Trusted to the external ceremony: %s\n", strings.Join(self.External, ", "))
+	out += fmt.Sprintf("  Trusted to this machine (local contribution): %s\n", strings.Join(self.Local, ", "))
+	out += fmt.Sprintf("  Nothing-up-my-sleeve (%s): %s", GENERATORS_LABEL, strings.Join(self.Derived, ", "))
+	return out
+}
+
+// Imports a snarkjs powers of tau file (.ptau) over BLS12-381.
+// A ptau of power p holds 2^p G2 powers, thus it supports ELL up to p-1.
+func (self *BpAcc) ImportPtau(ncores uint8, L uint64, ptauPath string, folderPath string) ImportReport {
+	report, err := self.TryImportPtau(ncores, L, ptauPath, folderPath)
+	check(err)
+	return report
+}
+
+// Same as ImportPtau, but a malformed transcript or an I/O failure is returned as a *FileError instead of panicking.
+// The errors wrap ErrMissingFile, ErrShortRead, ErrInvalidPoint or ErrFormat.
+func (self *BpAcc) TryImportPtau(ncores uint8, L uint64, ptauPath string, folderPath string) (ImportReport, error) {
+	self.setWorkers(ncores)
+	self.Init(L, "", folderPath)
+
+	f, err := openFile(ptauPath)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	defer f.Close()
+	power, err := self.readPtau(f, ptauPath)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report := ImportReport{Source: ptauPath, Format: "ptau", MaxELL: power - 1, ELL: L}
+	return report, self.importFinish(&report)
+}
+
+// Reads PK and VK, and returns the power of the transcript.
+func (self *BpAcc) readPtau(f *os.File, ptauPath string) (uint64, error) {
+	fail := func(err error) error {
+		return importError(ptauPath, err)
+	}
+	formatError := func(format string, a ...interface{}) error {
+		return fail(fmt.Errorf("%w: ptau: %s", ErrFormat, fmt.Sprintf(format, a...)))
+	}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return 0, fail(err)
+	}
+	if string(magic) != PTAU_MAGIC {
+		return 0, formatError("not a ptau file")
+	}
+
+	var version, nSections uint32
+	if err := binary.Read(f, binary.LittleEndian, &version); err != nil {
+		return 0, fail(err)
+	}
+	if err := binary.Read(f, binary.LittleEndian, &nSections); err != nil {
+		return 0, fail(err)
+	}
+
+	// Section type -> offset of its content
+	sections := make(map[uint32]int64)
+	offset := int64(12)
+	for i := uint32(0); i < nSections; i++ {
+		var sectionType uint32
+		var sectionSize uint64
+		if err := binary.Read(f, binary.LittleEndian, &sectionType); err != nil {
+			return 0, fail(err)
+		}
+		if err := binary.Read(f, binary.LittleEndian, &sectionSize); err != nil {
+			return 0, fail(err)
+		}
+		offset += 12
+		sections[sectionType] = offset
+		offset += int64(sectionSize)
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return 0, fail(err)
+		}
+	}
+	for _, s := range []uint32{PTAU_SECTION_HEADER, PTAU_SECTION_TAU_G1, PTAU_SECTION_TAU_G2} {
+		if _, ok := sections[s]; !ok {
+			return 0, formatError("missing section %d", s)
+		}
+	}
+
+	// Header: n8, q, power, ceremonyPower
+	if _, err := f.Seek(sections[PTAU_SECTION_HEADER], io.SeekStart); err != nil {
+		return 0, fail(err)
+	}
+	var n8, power uint32
+	if err := binary.Read(f, binary.LittleEndian, &n8); err != nil {
+		return 0, fail(err)
+	}
+	if n8 == 0 || n8 > 1024 {
+		return 0, formatError("field elements of %d bytes", n8)
+	}
+	qBytes := make([]byte, n8)
+	if _, err := io.ReadFull(f, qBytes); err != nil {
+		return 0, fail(err)
+	}
+	if err := binary.Read(f, binary.LittleEndian, &power); err != nil {
+		return 0, fail(err)
+	}
+
+	q := leToBig(qBytes)
+	if q.String() != mcl.GetFieldOrder() {
+		return 0, formatError("the transcript is not over BLS12-381")
+	}
+	if power == 0 || uint64(power)-1 < self.ELL {
+		return 0, formatError("There is not enough to read! Found: %d, Wants: %d", int64(power)-1, self.ELL)
+	}
+
+	// Points are stored as little-endian Montgomery coordinates.
+	rInv := new(big.Int).Lsh(big.NewInt(1), uint(8*n8))
+	rInv.ModInverse(rInv, q)
+	buf := make([]byte, n8)
+	var readErr error
+	coordinate := func(r *bufio.Reader, x *mcl.Fp) {
+		if readErr != nil {
+			return
+		}
+		if _, readErr = io.ReadFull(r, buf); readErr != nil {
+			return
+		}
+		v := leToBig(buf)
+		if v.Cmp(q) >= 0 {
+			readErr = fmt.Errorf("%w: coordinate out of range", ErrInvalidPoint)
+			return
+		}
+		v.Mul(v, rInv).Mod(v, q)
+		readErr = x.SetString(v.String(), 10)
+	}
+
+	if _, err := f.Seek(sections[PTAU_SECTION_TAU_G1], io.SeekStart); err != nil {
+		return 0, fail(err)
+	}
+	r := bufio.NewReader(f)
+	for i := range self.PK {
+		coordinate(r, &self.PK[i].X)
+		coordinate(r, &self.PK[i].Y)
+		self.PK[i].Z.SetInt64(1)
+	}
+
+	if _, err := f.Seek(sections[PTAU_SECTION_TAU_G2], io.SeekStart); err != nil {
+		return 0, fail(err)
+	}
+	r = bufio.NewReader(f)
+	for i := range self.VK {
+		coordinate(r, &self.VK[i].X.D[0])
+		coordinate(r, &self.VK[i].X.D[1])
+		coordinate(r, &self.VK[i].Y.D[0])
+		coordinate(r, &self.VK[i].Y.D[1])
+		self.VK[i].Z.D[0].SetInt64(1)
+		self.VK[i].Z.D[1].Clear()
+	}
+	if readErr != nil {
+		return 0, fail(readErr)
+	}
+	return uint64(power), nil
+}
+
+type kzgTranscript struct {
+	NumG1Powers uint64 `json:"numG1Powers"`
+	NumG2Powers uint64 `json:"numG2Powers"`
+	PowersOfTau struct {
+		G1Powers []string `json:"G1Powers"`
+		G2Powers []string `json:"G2Powers"`
+	} `json:"powersOfTau"`
+}
+
+// Imports the JSON transcript of a KZG ceremony (hex encoded, compressed points in the zcash format).
+// The first sub-transcript with enough powers is used.
+// (NOTE): These ceremonies publish few G2 powers, e.g. 65 powers support ELL up to 6.
+func (self *BpAcc) ImportKZGJSON(ncores uint8, L uint64, jsonPath string, folderPath string) ImportReport {
+	report, err := self.TryImportKZGJSON(ncores, L, jsonPath, folderPath)
+	check(err)
+	return report
+}
+
+// Same as ImportKZGJSON, but a malformed transcript or an I/O failure is returned as a *FileError instead of panicking.
+func (self *BpAcc) TryImportKZGJSON(ncores uint8, L uint64, jsonPath string, folderPath string) (ImportReport, error) {
+	self.setWorkers(ncores)
+	self.Init(L, "", folderPath)
+
+	data, err := os.ReadFile(jsonPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ImportReport{}, &FileError{jsonPath, ErrMissingFile}
+	}
+	if err != nil {
+		return ImportReport{}, &FileError{jsonPath, err}
+	}
+
+	var transcript struct {
+		Transcripts []kzgTranscript `json:"transcripts"`
+	}
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return ImportReport{}, &FileError{jsonPath, fmt.Errorf("%w: %v", ErrFormat, err)}
+	}
+
+	var chosen *kzgTranscript
+	maxEll := uint64(0)
+	for i := range transcript.Transcripts {
+		tr := &transcript.Transcripts[i]
+		n := minUint64(uint64(len(tr.PowersOfTau.G1Powers)), uint64(len(tr.PowersOfTau.G2Powers)))
+		if n < 2 {
+			continue
+		}
+		ell := uint64(math.Floor(math.Log2(float64(n - 1))))
+		if ell > maxEll {
+			maxEll = ell
+		}
+		if chosen == nil && n >= self.Q+1 {
+			chosen = tr
+		}
+	}
+	if chosen == nil {
+		return ImportReport{}, &FileError{jsonPath, fmt.Errorf("%w: KZG: There is not enough to read! Found: %d, Wants: %d", ErrFormat, maxEll, L)}
+	}
+
+	g1, err := decodeHexPoints(chosen.PowersOfTau.G1Powers[:len(self.PK)])
+	if err == nil {
+		var g2 [][]byte
+		g2, err = decodeHexPoints(chosen.PowersOfTau.G2Powers[:len(self.VK)])
+		if err == nil {
+			err = decodeZcash(g1, g2, self.PK, self.VK)
+		}
+	}
+	if err != nil {
+		return ImportReport{}, &FileError{jsonPath, err}
+	}
+
+	report := ImportReport{Source: jsonPath, Format: "kzg-json", MaxELL: maxEll, ELL: L}
+	return report, self.importFinish(&report)
+}
+
+func decodeHexPoints(points []string) ([][]byte, error) {
+	out := make([][]byte, len(points))
+	for i := range points {
+		buf, err := hex.DecodeString(strings.TrimPrefix(points[i], "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: power %d: %v", ErrFormat, i, err)
+		}
+		out[i] = buf
+	}
+	return out, nil
+}
+
+// Flags in the top three bits of the first byte of a zcash point.
+const ZCASH_COMPRESSED = 0x80
+const ZCASH_INFINITY = 0x40
+const ZCASH_LARGEST_Y = 0x20
+
+// Decodes the compressed points of the zcash format by hand, as mcl reads them only in its process-wide ETH mode.
+// x is big-endian (x_c1 then x_c0 on G2), and y is the root of x^3 + 4 (x^3 + 4(1 + i) on G2) chosen by ZCASH_LARGEST_Y.
+// The points are checked to be on the curve and in the subgroup by importFinish.
+func decodeZcash(g1 [][]byte, g2 [][]byte, PK []mcl.G1, VK []mcl.G2) error {
+	p, _ := new(big.Int).SetString(mcl.GetFieldOrder(), 10)
+	for i := range g1 {
+		if err := decodeZcashG1(&PK[i], g1[i], p); err != nil {
+			return fmt.Errorf("%w: G1 power %d: %v", ErrInvalidPoint, i, err)
+		}
+	}
+	for i := range g2 {
+		if err := decodeZcashG2(&VK[i], g2[i], p); err != nil {
+			return fmt.Errorf("%w: G2 power %d: %v", ErrInvalidPoint, i, err)
+		}
+	}
+	return nil
+}
+
+func decodeZcashG1(P *mcl.G1, buf []byte, p *big.Int) error {
+	flags, x, err := zcashSplit(buf, G1_COMPRESSED_SIZE)
+	if err != nil || flags&ZCASH_INFINITY != 0 {
+		P.Clear()
+		return err
+	}
+	if err := zcashFp(&P.X, x, p); err != nil {
+		return err
+	}
+
+	var y2, b mcl.Fp
+	mcl.FpSqr(&y2, &P.X)
+	mcl.FpMul(&y2, &y2, &P.X)
+	b.SetInt64(4)
+	mcl.FpAdd(&y2, &y2, &b)
+	if !mcl.FpSquareRoot(&P.Y, &y2) {
+		return errors.New("not on the curve")
+	}
+	if fpLargest(&P.Y, p) != (flags&ZCASH_LARGEST_Y != 0) {
+		mcl.FpNeg(&P.Y, &P.Y)
+	}
+	P.Z.SetInt64(1)
+	return nil
+}
+
+func decodeZcashG2(P *mcl.G2, buf []byte, p *big.Int) error {
+	flags, x, err := zcashSplit(buf, G2_COMPRESSED_SIZE)
+	if err != nil || flags&ZCASH_INFINITY != 0 {
+		P.Clear()
+		return err
+	}
+	if err := zcashFp(&P.X.D[1], x[:G1_COMPRESSED_SIZE], p); err != nil {
+		return err
+	}
+	if err := zcashFp(&P.X.D[0], x[G1_COMPRESSED_SIZE:], p); err != nil {
+		return err
+	}
+
+	var y2, b mcl.Fp2
+	mcl.Fp2Sqr(&y2, &P.X)
+	mcl.Fp2Mul(&y2, &y2, &P.X)
+	b.D[0].SetInt64(4)
+	b.D[1].SetInt64(4)
+	mcl.Fp2Add(&y2, &y2, &b)
+	if !mcl.Fp2SquareRoot(&P.Y, &y2) {
+		return errors.New("not on the curve")
+	}
+	// Lexicographic order: y_c1 first, y_c0 if y_c1 is zero
+	largest := fpLargest(&P.Y.D[1], p)
+	if P.Y.D[1].IsZero() {
+		largest = fpLargest(&P.Y.D[0], p)
+	}
+	if largest != (flags&ZCASH_LARGEST_Y != 0) {
+		mcl.Fp2Neg(&P.Y, &P.Y)
+	}
+	P.Z.D[0].SetInt64(1)
+	P.Z.D[1].Clear()
+	return nil
+}
+
+// Splits a compressed point into its flags and the bytes of x.
+func zcashSplit(buf []byte, size int) (byte, []byte, error) {
+	if len(buf) != size {
+		return 0, nil, fmt.Errorf("%d bytes, wants %d", len(buf), size)
+	}
+	flags := buf[0] & (ZCASH_COMPRESSED | ZCASH_INFINITY | ZCASH_LARGEST_Y)
+	x := append([]byte{buf[0] &^ flags}, buf[1:]...)
+	if flags&ZCASH_COMPRESSED == 0 {
+		return 0, nil, errors.New("not compressed")
+	}
+	if flags&ZCASH_INFINITY != 0 && (flags&ZCASH_LARGEST_Y != 0 || new(big.Int).SetBytes(x).Sign() != 0) {
+		return 0, nil, errors.New("malformed point at infinity")
+	}
+	return flags, x, nil
+}
+
+// Sets x from big-endian bytes, which have to be reduced.
+func zcashFp(x *mcl.Fp, buf []byte, p *big.Int) error {
+	v := new(big.Int).SetBytes(buf)
+	if v.Cmp(p) >= 0 {
+		return errors.New("coordinate out of range")
+	}
+	return x.SetString(v.Text(16), 16)
+}
+
+// Whether y > (p - 1) / 2.
+func fpLargest(y *mcl.Fp, p *big.Int) bool {
+	v, _ := new(big.Int).SetString(y.GetString(16), 16)
+	return v.Lsh(v, 1).Cmp(p) > 0
+}
+
+// Errors of the transcript at path.
+func importError(path string, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = ErrShortRead
+	}
+	return &FileError{path, err}
+}
+
+// Derives everything else from the imported PK and VK and saves the folder.
+func (self *BpAcc) importFinish(report *ImportReport) error {
+
+	for i := range self.PK {
+		if self.PK[i].IsZero() || !self.PK[i].IsValid() || !self.PK[i].IsValidOrder() {
+			return &FileError{report.Source, fmt.Errorf("%w: PK at index %d", ErrInvalidPoint, i)}
+		}
+		if self.VK[i].IsZero() || !self.VK[i].IsValid() || !self.VK[i].IsValidOrder() {
+			return &FileError{report.Source, fmt.Errorf("%w: VK at index %d", ErrInvalidPoint, i)}
+		}
+	}
+	// PK and VK have to come from the same tau
+	if !MultiPairing2(self.PK[1], self.VK[0], self.PK[0], self.VK[1]) {
+		return &FileError{report.Source, fmt.Errorf("%w: PK and VK do not share the same trapdoor", ErrFormat)}
+	}
+
+	self.G = self.PK[0]
+	self.H = self.VK[0]
+	mcl.G1Neg(&self.Gneg, &self.G)
+	mcl.G2Neg(&self.Hneg, &self.H)
+	mcl.Pairing(&self.IdGT, &self.G, &self.H)
+	mcl.GTInv(&self.InvIdGT, &self.IdGT)
+
+	self.A, self.B = DeriveSpareGenerators(GENERATORS_LABEL)
+
+	// Local contribution: alpha for the KEA and rho for PedH = h^rho
+	var rho mcl.Fr
+	self.randomFr(&self.Alpha)
+	self.randomFr(&rho)
+	mcl.G1Mul(&self.PKAlpha[0], &self.G, &self.Alpha)
+	mcl.G2Mul(&self.PedH, &self.H, &rho)
+
+	var wg sync.WaitGroup
+	num := self.Q + 1
+	step := uint64(math.Ceil(float64(num) / float64(self.workers())))
+	for start := uint64(0); start < num; start += step {
+		stop := minUint64(start+step, num)
+		wg.Add(1)
+		go func(start uint64, stop uint64) {
+			defer wg.Done()
+			for i := start; i < stop; i++ {
+				mcl.G2Mul(&self.VKAlpha[i], &self.VK[i], &self.Alpha)
+				mcl.G2Mul(&self.PedVK[i], &self.VK[i], &rho)
+				mcl.G2Mul(&self.PedVKAlpha[i], &self.PedVK[i], &self.Alpha)
+			}
+		}(start, stop)
+	}
+	wg.Wait()
+	rho.Clear()
+	self.ClearTrapdoors()
+
+	// Also catches a transcript whose powers are not consistent
+	if err := self.VerifyParams(); err != nil {
+		return &FileError{report.Source, fmt.Errorf("%w: %v", ErrFormat, err)}
+	}
+
+	if err := self.savePublic(); err != nil {
+		return err
+	}
+	if err := self.saveSegments(); err != nil {
+		return err
+	}
+	if err := self.saveManifest(); err != nil {
+		return err
+	}
+
+	report.External = []string{"PK", "VK", "G", "H"}
+	report.Local = []string{"VKAlpha", "PKAlpha", "PedH", "PedVK", "PedVKAlpha"}
+	report.Derived = []string{"A", "B"}
+	self.logln(report.String())
+	return nil
+}
+
+func leToBig(buf []byte) *big.Int {
+	be := make([]byte, len(buf))
+	for i := range buf {
+		be[len(buf)-1-i] = buf[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
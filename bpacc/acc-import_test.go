@@ -0,0 +1,251 @@
+package bpacc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Hex encoded points in the zcash format, as published by the KZG ceremonies.
+func zcashHex(PK []mcl.G1, VK []mcl.G2) ([]string, []string) {
+	p, _ := new(big.Int).SetString(mcl.GetFieldOrder(), 10)
+	be := func(x *mcl.Fp) []byte {
+		v, _ := new(big.Int).SetString(x.GetString(16), 16)
+		return v.FillBytes(make([]byte, G1_COMPRESSED_SIZE))
+	}
+
+	var g1, g2 []string
+	for i := range PK {
+		var P mcl.G1
+		mcl.G1Normalize(&P, &PK[i])
+		buf := be(&P.X)
+		buf[0] |= ZCASH_COMPRESSED
+		if fpLargest(&P.Y, p) {
+			buf[0] |= ZCASH_LARGEST_Y
+		}
+		g1 = append(g1, "0x"+hex.EncodeToString(buf))
+	}
+	for i := range VK {
+		var P mcl.G2
+		mcl.G2Normalize(&P, &VK[i])
+		buf := append(be(&P.X.D[1]), be(&P.X.D[0])...)
+		buf[0] |= ZCASH_COMPRESSED
+		if fpLargest(&P.Y.D[1], p) || (P.Y.D[1].IsZero() && fpLargest(&P.Y.D[0], p)) {
+			buf[0] |= ZCASH_LARGEST_Y
+		}
+		g2 = append(g2, "0x"+hex.EncodeToString(buf))
+	}
+	return g1, g2
+}
+
+func writeKZGJSON(t *testing.T, g1 []string, g2 []string) string {
+	var tr kzgTranscript
+	tr.PowersOfTau.G1Powers, tr.PowersOfTau.G2Powers = g1, g2
+	tr.NumG1Powers = uint64(len(g1))
+	tr.NumG2Powers = uint64(len(g2))
+	data, err := json.Marshal(map[string][]kzgTranscript{"transcripts": {tr}})
+	check(err)
+	jsonPath := filepath.Join(t.TempDir(), "transcript.json")
+	check(os.WriteFile(jsonPath, data, 0644))
+	return jsonPath
+}
+
+// A snarkjs ptau file of the given power holding PK and VK: header, tau G1 and tau G2 sections,
+// with the coordinates in little-endian Montgomery form.
+func writePtau(t *testing.T, power uint32, PK []mcl.G1, VK []mcl.G2) string {
+	const n8 = 48
+	q, _ := new(big.Int).SetString(mcl.GetFieldOrder(), 10)
+	R := new(big.Int).Lsh(big.NewInt(1), 8*n8)
+	le := func(v *big.Int) []byte {
+		buf := v.FillBytes(make([]byte, n8))
+		for i, j := 0, n8-1; i < j; i, j = i+1, j-1 {
+			buf[i], buf[j] = buf[j], buf[i]
+		}
+		return buf
+	}
+	coordinate := func(w *bytes.Buffer, x *mcl.Fp) {
+		v, _ := new(big.Int).SetString(x.GetString(10), 10)
+		w.Write(le(v.Mul(v, R).Mod(v, q)))
+	}
+
+	var header, tauG1, tauG2 bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, uint32(n8))
+	header.Write(le(q))
+	binary.Write(&header, binary.LittleEndian, power)
+	binary.Write(&header, binary.LittleEndian, power)
+	for i := range PK {
+		var P mcl.G1
+		mcl.G1Normalize(&P, &PK[i])
+		coordinate(&tauG1, &P.X)
+		coordinate(&tauG1, &P.Y)
+	}
+	for i := range VK {
+		var P mcl.G2
+		mcl.G2Normalize(&P, &VK[i])
+		coordinate(&tauG2, &P.X.D[0])
+		coordinate(&tauG2, &P.X.D[1])
+		coordinate(&tauG2, &P.Y.D[0])
+		coordinate(&tauG2, &P.Y.D[1])
+	}
+
+	var out bytes.Buffer
+	out.WriteString(PTAU_MAGIC)
+	binary.Write(&out, binary.LittleEndian, uint32(1))
+	binary.Write(&out, binary.LittleEndian, uint32(3))
+	for k, section := range []*bytes.Buffer{&header, &tauG1, &tauG2} {
+		binary.Write(&out, binary.LittleEndian, uint32(k+1))
+		binary.Write(&out, binary.LittleEndian, uint64(section.Len()))
+		out.Write(section.Bytes())
+	}
+	path := filepath.Join(t.TempDir(), "transcript.ptau")
+	check(os.WriteFile(path, out.Bytes(), 0644))
+	return path
+}
+
+// Loads the imported folder and checks it against the external powers.
+func checkImported(t *testing.T, l uint64, folder string, external *BpAcc) {
+	var acc BpAcc
+	acc.KeyGenLoadPublic(8, l, folder)
+	for i := range acc.VK {
+		if !acc.PK[i].IsEqual(&external.PK[i]) || !acc.VK[i].IsEqual(&external.VK[i]) {
+			t.Fatalf("Imported powers differ at index %d", i)
+		}
+		if !MultiPairing2(acc.G, acc.VKAlpha[i], acc.PKAlpha[0], acc.VK[i]) {
+			t.Fatalf("VKAlpha is not consistent at index %d", i)
+		}
+	}
+
+	// Only the spare generators are derived locally
+	if err := acc.CheckSpareGenerators(GENERATORS_LABEL); err != nil {
+		t.Errorf("Imported spare generators did not check: %s", err)
+	}
+	if err := acc.CheckGenerators(GENERATORS_LABEL); err == nil {
+		t.Errorf("Imported G and H checked as derived")
+	}
+
+	n := uint64(1 << 3)
+	elements := PopulateRandom(n)
+	digest, _ := acc.Commit(elements)
+	X, I := elements[:n/2], elements[n/2:]
+	proofs := acc.MemProve(X, I)
+	for k := range I {
+		if !acc.MemVerifySingle(digest, I[k], proofs[k]) {
+			t.Errorf("Proof did not verify %d", k)
+			break
+		}
+	}
+}
+
+func TestImportKZGJSON(t *testing.T) {
+
+	l := uint64(4)
+	var external BpAcc
+	external.KeyGenMem(8, l+1, "external ceremony")
+
+	g1, g2 := zcashHex(external.PK, external.VK)
+	jsonPath := writeKZGJSON(t, g1, g2)
+	folder := t.TempDir()
+	var importer BpAcc
+	report := importer.ImportKZGJSON(8, l, jsonPath, folder)
+	if report.MaxELL != l+1 || report.ELL != l {
+		t.Errorf("Unexpected report: %s", report.String())
+	}
+	checkImported(t, l, folder, &external)
+
+	// Without the compression flag, with x >= p, a malformed infinity, a short point
+	good := g1[3]
+	p, _ := new(big.Int).SetString(mcl.GetFieldOrder(), 10)
+	for k, bad := range []string{
+		"0x00" + good[4:],
+		"0x" + hex.EncodeToString(new(big.Int).SetBit(p, 383, 1).FillBytes(make([]byte, G1_COMPRESSED_SIZE))),
+		"0xc0" + good[4:],
+		good[:len(good)-2],
+	} {
+		g1[3] = bad
+		if _, err := importer.TryImportKZGJSON(8, l, writeKZGJSON(t, g1, g2), t.TempDir()); !errors.Is(err, ErrInvalidPoint) {
+			t.Errorf("Bad point %d reported as: %v", k, err)
+		}
+	}
+	g1[3] = "0xzz"
+	if _, err := importer.TryImportKZGJSON(8, l, writeKZGJSON(t, g1, g2), t.TempDir()); !errors.Is(err, ErrFormat) {
+		t.Errorf("Bad hex reported as: %v", err)
+	}
+	if _, err := importer.TryImportKZGJSON(8, l+2, jsonPath, t.TempDir()); !errors.Is(err, ErrFormat) {
+		t.Errorf("Short transcript reported as: %v", err)
+	}
+	var fileErr *FileError
+	if _, err := importer.TryImportKZGJSON(8, l, filepath.Join(t.TempDir(), "none.json"), t.TempDir()); !errors.As(err, &fileErr) || !errors.Is(err, ErrMissingFile) {
+		t.Errorf("Missing transcript reported as: %v", err)
+	}
+}
+
+func TestImportPtau(t *testing.T) {
+
+	l := uint64(4)
+	var external BpAcc
+	external.KeyGenMem(8, l+1, "external ceremony")
+
+	ptauPath := writePtau(t, uint32(l+1), external.PK, external.VK)
+	folder := t.TempDir()
+	var importer BpAcc
+	report := importer.ImportPtau(8, l, ptauPath, folder)
+	if report.MaxELL != l || report.ELL != l || report.Format != "ptau" {
+		t.Errorf("Unexpected report: %s", report.String())
+	}
+	checkImported(t, l, folder, &external)
+
+	if _, err := importer.TryImportPtau(8, l+1, ptauPath, t.TempDir()); !errors.Is(err, ErrFormat) {
+		t.Errorf("Too large ELL reported as: %v", err)
+	}
+	data, err := os.ReadFile(ptauPath)
+	check(err)
+	truncated := filepath.Join(t.TempDir(), "truncated.ptau")
+	check(os.WriteFile(truncated, data[:300], 0644))
+	var fileErr *FileError
+	if _, err := importer.TryImportPtau(8, l, truncated, t.TempDir()); !errors.As(err, &fileErr) || !errors.Is(err, ErrShortRead) {
+		t.Errorf("Truncated ptau reported as: %v", err)
+	}
+	data[0] = 'x'
+	check(os.WriteFile(truncated, data, 0644))
+	if _, err := importer.TryImportPtau(8, l, truncated, t.TempDir()); !errors.Is(err, ErrFormat) {
+		t.Errorf("Bad magic reported as: %v", err)
+	}
+}
+
+// The generators of BLS12-381 in the zcash format and their affine coordinates.
+func TestDecodeZcash(t *testing.T) {
+	g1, err := hex.DecodeString("97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb")
+	check(err)
+	g2, err := hex.DecodeString("93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e" +
+		"024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8")
+	check(err)
+
+	PK := make([]mcl.G1, 1)
+	VK := make([]mcl.G2, 1)
+	if err := decodeZcash([][]byte{g1}, [][]byte{g2}, PK, VK); err != nil {
+		t.Fatalf("Generators did not decode: %s", err)
+	}
+	for _, c := range []struct {
+		x    *mcl.Fp
+		want string
+	}{
+		{&PK[0].X, "17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"},
+		{&PK[0].Y, "8b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1"},
+		{&VK[0].X.D[0], "24aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8"},
+		{&VK[0].X.D[1], "13e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e"},
+		{&VK[0].Y.D[0], "ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801"},
+		{&VK[0].Y.D[1], "606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be"},
+	} {
+		if got := c.x.GetString(16); got != c.want {
+			t.Errorf("Decoded coordinate %s, wants %s", got, c.want)
+		}
+	}
+}
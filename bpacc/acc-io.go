@@ -0,0 +1,182 @@
+package bpacc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Errors of the parameter I/O. They are wrapped in a FileError, thus check them with errors.Is.
+var (
+	ErrMissingFile  = errors.New("missing file")
+	ErrShortRead    = errors.New("short read")
+	ErrInvalidPoint = errors.New("invalid point")
+	ErrEllMismatch  = errors.New("ELL mismatch")
+	ErrFormat       = errors.New("bad file format")
+)
+
+// Error of a single parameter file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (self *FileError) Error() string {
+	return self.Path + ": " + self.Err.Error()
+}
+
+func (self *FileError) Unwrap() error {
+	return self.Err
+}
+
+func openFile(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &FileError{path, ErrMissingFile}
+	}
+	if err != nil {
+		return nil, &FileError{path, err}
+	}
+	return f, nil
+}
+
+func createFile(path string) (*os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, &FileError{path, err}
+	}
+	return f, nil
+}
+
+// Reads serialized values one after the other, the points in the given encoding.
+// The first error sticks, later reads are skipped, and it is reported by Err.
+type pointReader struct {
+	r        io.Reader
+	path     string
+	encoding PointEncoding
+	err      error
+}
+
+func (self *pointReader) read(size int) []byte {
+	if self.err != nil {
+		return nil
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(self.r, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrShortRead
+		}
+		self.err = &FileError{self.path, err}
+		return nil
+	}
+	return data
+}
+
+func (self *pointReader) deserialized(err error) {
+	if err != nil && self.err == nil {
+		self.err = &FileError{self.path, fmt.Errorf("%w: %v", ErrInvalidPoint, err)}
+	}
+}
+
+func (self *pointReader) Fr(x *mcl.Fr) {
+	if data := self.read(GetFrByteSize()); data != nil {
+		self.deserialized(x.Deserialize(data))
+	}
+}
+
+func (self *pointReader) G1(x *mcl.G1) {
+	if data := self.read(self.encoding.G1Size()); data != nil {
+		self.deserialized(self.encoding.DecodeG1(x, data))
+	}
+}
+
+func (self *pointReader) G2(x *mcl.G2) {
+	if data := self.read(self.encoding.G2Size()); data != nil {
+		self.deserialized(self.encoding.DecodeG2(x, data))
+	}
+}
+
+func (self *pointReader) GT(x *mcl.GT) {
+	if data := self.read(GetGTByteSize()); data != nil {
+		self.deserialized(x.Deserialize(data))
+	}
+}
+
+func (self *pointReader) Err() error {
+	return self.err
+}
+
+// Same as pointReader, for writing.
+type pointWriter struct {
+	w        io.Writer
+	path     string
+	encoding PointEncoding
+	err      error
+}
+
+func (self *pointWriter) Write(data []byte) {
+	if self.err != nil {
+		return
+	}
+	if _, err := self.w.Write(data); err != nil {
+		self.err = &FileError{self.path, err}
+	}
+}
+
+func (self *pointWriter) G1(x *mcl.G1) {
+	self.Write(self.encoding.EncodeG1(x))
+}
+
+func (self *pointWriter) G2(x *mcl.G2) {
+	self.Write(self.encoding.EncodeG2(x))
+}
+
+func (self *pointWriter) Err() error {
+	return self.err
+}
+
+// Runs goroutines and keeps the first error any of them returns.
+type errorGroup struct {
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	err   error
+	limit chan struct{}
+}
+
+// At most n goroutines run at once, Go blocks until one is done. Has to be called before Go.
+func (self *errorGroup) SetLimit(n int) {
+	self.limit = make(chan struct{}, n)
+}
+
+func (self *errorGroup) Go(f func() error) {
+	if self.limit != nil {
+		self.limit <- struct{}{}
+	}
+	self.wg.Add(1)
+	go func() {
+		defer self.wg.Done()
+		if self.limit != nil {
+			defer func() { <-self.limit }()
+		}
+		if err := f(); err != nil {
+			self.mu.Lock()
+			if self.err == nil {
+				self.err = err
+			}
+			self.mu.Unlock()
+		}
+	}()
+}
+
+// Waits for the goroutines started so far. It can be called again after more calls to Go.
+func (self *errorGroup) Wait() error {
+	self.wg.Wait()
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.err
+}
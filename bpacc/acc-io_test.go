@@ -0,0 +1,101 @@
+package bpacc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTryKeyGenLoad(t *testing.T) {
+
+	l := uint64(4)
+	folder := t.TempDir()
+	var acc BpAcc
+	if err := acc.TryKeyGen(8, l, "xyz", folder); err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded BpAcc
+	if err := loaded.TryKeyGenLoad(8, l, "xyz", folder); err != nil {
+		t.Fatalf("Fresh folder did not load: %s", err)
+	}
+	if !loaded.PK[5].IsEqual(&acc.PK[5]) || !loaded.PedVKAlpha[16].IsEqual(&acc.PedVKAlpha[16]) {
+		t.Errorf("Loaded parameters differ")
+	}
+
+	if err := loaded.TryKeyGenLoad(8, l, "xyz", t.TempDir()); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("Empty folder reported as: %v", err)
+	}
+	if err := loaded.TryKeyGenLoad(8, l+1, "xyz", folder); !errors.Is(err, ErrEllMismatch) {
+		t.Errorf("Larger ELL reported as: %v", err)
+	}
+
+	// Damage the files, then re-sign the folder so that the loader itself has to notice.
+	trapdoor := folder + TRAPDOORNAME
+	segment := folder + fmt.Sprintf(VRK_NAME, 0)
+	trapdoorData, err := os.ReadFile(trapdoor)
+	check(err)
+	segmentData, err := os.ReadFile(segment)
+	check(err)
+
+	invalid := append([]byte{}, segmentData...)
+	for i := len(invalid) - acc.GetG2ByteSize(); i < len(invalid); i++ {
+		invalid[i] = 0xff
+	}
+	corruptions := []struct {
+		path     string
+		data     []byte
+		original []byte
+		err      error
+	}{
+		{trapdoor, trapdoorData[:len(trapdoorData)-1], trapdoorData, ErrShortRead},
+		{segment, invalid, segmentData, ErrInvalidPoint},
+	}
+	for _, c := range corruptions {
+		check(os.WriteFile(c.path, c.data, 0644))
+		acc.SaveManifest()
+		err := loaded.TryKeyGenLoad(8, l, "xyz", folder)
+		check(os.WriteFile(c.path, c.original, 0644))
+
+		var ferr *FileError
+		if !errors.Is(err, c.err) || !errors.As(err, &ferr) || ferr.Path != c.path {
+			t.Errorf("Expected %v in %s, got: %v", c.err, c.path, err)
+		}
+	}
+}
+
+func TestKeyGenLoadPrefix(t *testing.T) {
+
+	saved := uint64(6)
+	folder := t.TempDir()
+	var acc BpAcc
+	acc.KeyGen(8, saved, "xyz", folder)
+
+	for l := uint64(0); l <= saved; l++ {
+		var loaded BpAcc
+		if err := loaded.TryKeyGenLoad(8, l, "xyz", folder); err != nil {
+			t.Fatalf("ELL %d did not load: %s", l, err)
+		}
+		if uint64(len(loaded.PK)) != loaded.Q+1 {
+			t.Fatalf("ELL %d: %d powers", l, len(loaded.PK))
+		}
+		for i := uint64(0); i <= loaded.Q; i++ {
+			if !loaded.PK[i].IsEqual(&acc.PK[i]) || !loaded.VK[i].IsEqual(&acc.VK[i]) ||
+				!loaded.VKAlpha[i].IsEqual(&acc.VKAlpha[i]) || !loaded.PedVK[i].IsEqual(&acc.PedVK[i]) ||
+				!loaded.PedVKAlpha[i].IsEqual(&acc.PedVKAlpha[i]) {
+				t.Fatalf("ELL %d: power %d differs", l, i)
+			}
+		}
+		if !loaded.IsParamsCorrect() {
+			t.Errorf("ELL %d: parameters are not correct", l)
+		}
+	}
+
+	var loaded BpAcc
+	err := loaded.TryKeyGenLoad(8, saved+1, "xyz", folder)
+	if !errors.Is(err, ErrEllMismatch) || !strings.Contains(err.Error(), "Found: 6, Wants: 7") {
+		t.Errorf("Larger ELL reported as: %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package bpacc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Folders written before the file headers (see acc-format.go) hold the same bytes as today, without a header:
+// trapdoors.data, public.data and the manager secret start with the ELL as a little-endian uint64,
+// the contribution records with their index, and the segment files hold nothing but the compressed points,
+// one after the other in file name order. Migrate puts a header in front of every file and writes the manifest,
+// after which the folder loads like any other.
+
+const LEGACY_PREFIX_SIZE = 8
+
+// Upgrades a folder written before the file headers, in place.
+// secretPath, if not empty, is the manager secret of the folder and is upgraded as well.
+// Files that already carry a header are kept, thus an interrupted migration is finished by running it again.
+// A folder with a manifest is left alone.
+func (self *BpAcc) Migrate(folderPath string, secretPath string) {
+	check(self.TryMigrate(folderPath, secretPath))
+}
+
+// Same as Migrate, but I/O failures are returned instead of panicking.
+func (self *BpAcc) TryMigrate(folderPath string, secretPath string) error {
+
+	legacy := &BpAcc{opts: self.opts, folderPath: folderPath}
+	if _, err := os.Stat(folderPath + MANIFESTNAME); err == nil {
+		return nil
+	}
+
+	ell, err := legacy.legacyEll()
+	if err != nil {
+		return err
+	}
+	legacy.ELL = ell
+
+	singles := []struct {
+		path      string
+		component string
+	}{
+		{folderPath + TRAPDOORNAME, TRAPDOOR_COMPONENT},
+		{folderPath + PUBLICNAME, PUBLIC_COMPONENT},
+		{secretPath, SECRET_COMPONENT},
+	}
+	for _, s := range singles {
+		if s.path == "" {
+			continue
+		}
+		migrated, err := hasHeader(s.path)
+		if errors.Is(err, ErrMissingFile) || migrated {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := migrateFile(s.path, NewFileHeader(s.component, ell, 0, 0, 0), LEGACY_PREFIX_SIZE); err != nil {
+			return err
+		}
+	}
+
+	num := uint64(1)<<ell + 1
+	for _, pattern := range SEGMENT_NAMES {
+		if err := legacy.migrateSegments(pattern, num); err != nil {
+			return err
+		}
+	}
+
+	files, err := filepath.Glob(folderPath + "/contrib-[0-9][0-9][0-9].data")
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		migrated, err := hasHeader(path)
+		if err != nil {
+			return err
+		}
+		if migrated {
+			continue
+		}
+		index, err := readLegacyPrefix(path)
+		if err != nil {
+			return err
+		}
+		if err := migrateFile(path, NewFileHeader(CONTRIBUTION_COMPONENT, ell, uint32(index), 0, 0), LEGACY_PREFIX_SIZE); err != nil {
+			return err
+		}
+	}
+
+	return legacy.saveManifest()
+}
+
+// The ELL of a legacy folder, as reported by trapdoors.data or public.data.
+func (self *BpAcc) legacyEll() (uint64, error) {
+	for _, name := range []string{TRAPDOORNAME, PUBLICNAME} {
+		path := self.folderPath + name
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		migrated, err := hasHeader(path)
+		if err != nil {
+			return 0, err
+		}
+		if migrated {
+			header, err := readFileHeader(path)
+			return header.ELL, err
+		}
+		return readLegacyPrefix(path)
+	}
+	return 0, &FileError{self.folderPath + PUBLICNAME, ErrMissingFile}
+}
+
+// Gives the segment files of a component the ranges they hold, in file name order, and checks that they hold num points.
+func (self *BpAcc) migrateSegments(pattern string, num uint64) error {
+
+	component := componentName(pattern)
+	files, err := filepath.Glob(self.folderPath + "/" + component + "-[0-9][0-9].data")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	pointSize := int64(POINT_COMPRESSED.G2Size())
+	if pattern == PRK_NAME {
+		pointSize = int64(POINT_COMPRESSED.G1Size())
+	}
+
+	next := uint64(0)
+	for _, path := range files {
+		var index uint32
+		if _, err := fmt.Sscanf(filepath.Base(path), component+"-%02d.data", &index); err != nil {
+			return &FileError{path, fmt.Errorf("%w: %v", ErrFormat, err)}
+		}
+
+		migrated, err := hasHeader(path)
+		if err != nil {
+			return err
+		}
+		if migrated {
+			header, err := readFileHeader(path)
+			if err != nil {
+				return err
+			}
+			if header.Start != next {
+				return &FileError{path, fmt.Errorf("%w: holds [%d, %d), wants to start at %d", ErrFormat, header.Start, header.Stop, next)}
+			}
+			next = header.Stop
+			continue
+		}
+
+		size, err := fileSize(path)
+		if err != nil {
+			return err
+		}
+		if size%pointSize != 0 {
+			return &FileError{path, fmt.Errorf("%w: %d bytes is not a whole number of points", ErrShortRead, size)}
+		}
+		stop := next + uint64(size/pointSize)
+		if err := migrateFile(path, NewFileHeader(component, self.ELL, index, next, stop), 0); err != nil {
+			return err
+		}
+		next = stop
+	}
+	if next != num {
+		return &FileError{self.folderPath, fmt.Errorf("%w: %s holds %d elements, wants %d", ErrMissingFile, component, next, num)}
+	}
+	return nil
+}
+
+// Whether the file starts with FORMAT_MAGIC, i.e. has been written or migrated since the file headers.
+func hasHeader(path string) (bool, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(FORMAT_MAGIC))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil
+	}
+	return string(magic) == FORMAT_MAGIC, nil
+}
+
+// Reads the uint64 at the start of a legacy file: the ELL, or the index of a contribution record.
+func readLegacyPrefix(path string) (uint64, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	data := make([]byte, LEGACY_PREFIX_SIZE)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return 0, &FileError{path, ErrShortRead}
+	}
+	return binary.LittleEndian.Uint64(data), nil
+}
+
+// Rewrites path as header followed by its bytes past skip.
+// The new file is written next to it and renamed over it, thus path is either legacy or migrated.
+func migrateFile(path string, header FileHeader, skip int64) error {
+
+	src, err := openFile(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if _, err := src.Seek(skip, io.SeekStart); err != nil {
+		return &FileError{path, err}
+	}
+
+	tmp := path + ".migrate"
+	dst, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return &FileError{tmp, err}
+	}
+	if info, err := src.Stat(); err == nil {
+		dst.Chmod(info.Mode())
+	}
+	err = WriteHeader(dst, header)
+	if err == nil {
+		_, err = io.Copy(dst, src)
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return &FileError{path, err}
+	}
+	return nil
+}
+
+// Whether the folder has no manifest but a headerless trapdoors.data or public.data, see Migrate.
+func (self *BpAcc) isLegacyFolder() bool {
+	for _, name := range []string{TRAPDOORNAME, PUBLICNAME} {
+		migrated, err := hasHeader(self.folderPath + name)
+		if errors.Is(err, ErrMissingFile) {
+			continue
+		}
+		return err == nil && !migrated
+	}
+	return false
+}
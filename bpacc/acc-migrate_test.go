@@ -0,0 +1,95 @@
+package bpacc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Strips the headers and the manifest, as the folders were written before the file headers.
+func makeLegacy(t *testing.T, folder string, secretPath string, ell uint64) map[string][]byte {
+
+	originals := make(map[string][]byte)
+	files, err := parameterFilesIn(folder)
+	check(err)
+	if secretPath != "" {
+		files = append(files, secretPath)
+	}
+
+	prefix := make([]byte, LEGACY_PREFIX_SIZE)
+	binary.LittleEndian.PutUint64(prefix, ell)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		check(err)
+		originals[path] = data
+
+		legacy := data[HEADER_SIZE:]
+		switch filepath.Base(path) {
+		case TRAPDOORNAME[1:], PUBLICNAME[1:], filepath.Base(secretPath):
+			legacy = append(append([]byte{}, prefix...), legacy...)
+		}
+		check(os.WriteFile(path, legacy, 0644))
+	}
+	check(os.Remove(folder + MANIFESTNAME))
+	return originals
+}
+
+func TestMigrate(t *testing.T) {
+
+	l := uint64(4)
+	folder := t.TempDir()
+	secretPath := t.TempDir() + "/manager.secret"
+	acc := NewBpAcc(Options{Segments: 3})
+	check(acc.TryKeyGenPublic(8, l, folder, secretPath))
+	originals := makeLegacy(t, folder, secretPath, l)
+
+	var loaded BpAcc
+	if err := loaded.TryKeyGenLoadPublic(8, l, folder); !errors.Is(err, ErrFormat) {
+		t.Fatalf("Legacy folder loaded, or unexpected error: %v", err)
+	}
+
+	// An interrupted migration: one segment is already done
+	segment := folder + "/prk-01.data"
+	check(os.WriteFile(segment, originals[segment], 0644))
+
+	if err := loaded.TryMigrate(folder, secretPath); err != nil {
+		t.Fatalf("Migrate failed: %s", err)
+	}
+	for path, data := range originals {
+		migrated, err := os.ReadFile(path)
+		check(err)
+		if !bytes.Equal(migrated, data) {
+			t.Errorf("%s differs from the file written with a header", path)
+		}
+	}
+
+	if err := loaded.TryKeyGenLoadPublic(8, l, folder); err != nil {
+		t.Fatalf("Migrated folder did not load: %s", err)
+	}
+	if err := loaded.VerifyManifest(); err != nil {
+		t.Errorf("Migrated folder did not verify: %s", err)
+	}
+	if err := loaded.loadManagerSecret(secretPath); err != nil {
+		t.Errorf("Migrated manager secret did not load: %s", err)
+	}
+
+	// Running it again changes nothing
+	if err := loaded.TryMigrate(folder, secretPath); err != nil {
+		t.Errorf("Second Migrate failed: %s", err)
+	}
+	if err := loaded.TryKeyGenLoadPublic(8, l, folder); err != nil {
+		t.Errorf("Folder did not load after a second Migrate: %s", err)
+	}
+
+	// Missing points are not papered over
+	folder = t.TempDir()
+	check(NewBpAcc(Options{Segments: 3}).TryKeyGenPublic(8, l, folder, ""))
+	makeLegacy(t, folder, "", l)
+	check(os.Remove(folder + "/vrk-02.data"))
+	if err := loaded.TryMigrate(folder, ""); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("Folder without its last segment migrated, or unexpected error: %v", err)
+	}
+}
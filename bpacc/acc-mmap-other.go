@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package bpacc
+
+import (
+	"io"
+	"os"
+)
+
+// No mmap here: the file is read in memory, points are still decoded on demand.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	_, err := io.ReadFull(f, data)
+	return data, err
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,451 @@
+package bpacc
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Memory-mapped storage of the powers.
+// KeyGenLoadMapped maps the segment files instead of deserializing them, and points are decoded
+// on demand, CHUNK_SIZE at a time. Thus a prover committing n elements only keeps PK[:n] in memory.
+// Decoded chunks are kept in a small LRU per component.
+// The points are checked when their chunk is decoded (except in trusted folders, see POINT_TRUSTED), not when
+// the files are mapped, thus a bad point is returned as an ErrInvalidPoint error by PKRange, VKRange, ...,
+// which work for both storages. The core code (Commit, MemProve, ...) panics with it instead, see pkRange.
+
+const CHUNK_SIZE = 1 << 12
+
+// A segment file that is mapped in memory.
+type mappedSegment struct {
+	fileName  string
+	start     uint64
+	stop      uint64
+	encoding  PointEncoding // Used for decoding, see BpAcc.reading
+	pointSize int
+	mapping   []byte // The whole file, as returned by mapFile
+	body      []byte // The points, right after the header
+}
+
+// All the segments of one component.
+// Segments appended by Extend may use another encoding than the first ones.
+type mappedComponent struct {
+	segments  []mappedSegment // Sorted by start
+	num       uint64          // Number of points that can be read
+	chunkSize uint64
+	cache     *chunkCache
+}
+
+// Storage of the five components.
+type mappedStore struct {
+	PK         mappedComponent
+	VK         mappedComponent
+	VKAlpha    mappedComponent
+	PedVK      mappedComponent
+	PedVKAlpha mappedComponent
+}
+
+// Same as KeyGenLoad or KeyGenLoadPublic (depending on the files in the folder), but the segments are mapped.
+// cacheChunks is the number of decoded chunks kept per component; 0 disables the cache.
+// PK, VK, VKAlpha, PedVK and PedVKAlpha stay nil; use PKRange and friends. Close releases the mappings.
+// The files are checked against the sizes and headers recorded in the manifest, but not hashed; call VerifyManifest for that.
+func (self *BpAcc) KeyGenLoadMapped(ncores uint8, L uint64, folderPath string, cacheChunks int) {
+	check(self.TryKeyGenLoadMapped(ncores, L, folderPath, cacheChunks))
+}
+
+func (self *BpAcc) TryKeyGenLoadMapped(ncores uint8, L uint64, folderPath string, cacheChunks int) error {
+	self.setWorkers(ncores)
+	self.initShared(L, "", folderPath)
+
+	// The points are checked when they are decoded, thus the files are not hashed
+	if err := self.verifyManifest(false); err != nil {
+		return err
+	}
+	var err error
+	if _, statErr := os.Stat(folderPath + TRAPDOORNAME); statErr == nil {
+		err = self.loadTrapdoor(L)
+	} else {
+		err = self.loadPublic(L)
+	}
+	if err != nil {
+		return err
+	}
+
+	store := &mappedStore{}
+	components := []struct {
+		pattern string
+		c       *mappedComponent
+		g1      bool
+	}{
+		{PRK_NAME, &store.PK, true},
+		{VRK_NAME, &store.VK, false},
+		{VRK_KEA_NAME, &store.VKAlpha, false},
+		{PED_VRK_NAME, &store.PedVK, false},
+		{PED_VRK_KEA_NAME, &store.PedVKAlpha, false},
+	}
+	for _, component := range components {
+		files, err := filepath.Glob(folderPath + "/" + componentName(component.pattern) + "-[0-9][0-9].data")
+		if err == nil && len(files) == 0 {
+			err = &FileError{folderPath, fmt.Errorf("%w: Could not find the %s files.", ErrMissingFile, componentName(component.pattern))}
+		}
+		if err == nil {
+			err = component.c.open(files, self.Q+1, component.g1, cacheChunks, self.reading)
+		}
+		if err != nil {
+			store.close()
+			return err
+		}
+	}
+	self.store = store
+	return nil
+}
+
+// Releases the mappings of KeyGenLoadMapped. It is a no-op for the in-memory storage.
+func (self *BpAcc) Close() error {
+	if self.store == nil {
+		return nil
+	}
+	err := self.store.close()
+	self.store = nil
+	return err
+}
+
+func (self *mappedStore) close() error {
+	var err error
+	for _, c := range []*mappedComponent{&self.PK, &self.VK, &self.VKAlpha, &self.PedVK, &self.PedVKAlpha} {
+		for i := range c.segments {
+			if e := unmapFile(c.segments[i].mapping); e != nil && err == nil {
+				err = &FileError{c.segments[i].fileName, e}
+			}
+		}
+		c.segments = nil
+	}
+	return err
+}
+
+func (self *mappedComponent) open(files []string, num uint64, g1 bool, cacheChunks int, reading func(PointEncoding) PointEncoding) error {
+
+	parts, err := segmentParts(files, num)
+	if err != nil {
+		return err
+	}
+
+	self.num = num
+	self.chunkSize = CHUNK_SIZE
+	self.cache = newChunkCache(cacheChunks)
+	for _, part := range parts {
+		pointSize := part.encoding.G2Size()
+		if g1 {
+			pointSize = part.encoding.G1Size()
+		}
+		f, err := openFile(part.fileName)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return &FileError{part.fileName, err}
+		}
+		size := int64(HEADER_SIZE) + int64(part.stop-part.start)*int64(pointSize)
+		if info.Size() < size {
+			f.Close()
+			return &FileError{part.fileName, fmt.Errorf("%w: %d bytes, wants %d", ErrShortRead, info.Size(), size)}
+		}
+		mapping, err := mapFile(f, int(info.Size()))
+		f.Close()
+		if err != nil {
+			return &FileError{part.fileName, err}
+		}
+		self.segments = append(self.segments, mappedSegment{
+			fileName:  part.fileName,
+			start:     part.start,
+			stop:      part.stop,
+			encoding:  reading(part.encoding),
+			pointSize: pointSize,
+			mapping:   mapping,
+			body:      mapping[HEADER_SIZE:],
+		})
+	}
+	return nil
+}
+
+// Raw bytes of the i-th point, along with its segment.
+func (self *mappedComponent) point(i uint64) ([]byte, *mappedSegment) {
+	k := sort.Search(len(self.segments), func(k int) bool { return self.segments[k].stop > i })
+	segment := &self.segments[k]
+	offset := int(i-segment.start) * segment.pointSize
+	return segment.body[offset : offset+segment.pointSize], segment
+}
+
+func (self *mappedComponent) checkRange(start uint64, stop uint64) {
+	if start > stop || stop > self.num {
+		panic(fmt.Sprintf("Range [%d, %d) is out of the %d mapped powers", start, stop, self.num))
+	}
+}
+
+// Decodes (and checks) the k-th chunk. Only chunks without bad points are cached.
+func (self *mappedComponent) g1Chunk(k uint64) ([]mcl.G1, error) {
+	if chunk, ok := self.cache.get(k); ok {
+		return chunk.([]mcl.G1), nil
+	}
+	start := k * self.chunkSize
+	stop := minUint64(start+self.chunkSize, self.num)
+	chunk := make([]mcl.G1, stop-start)
+	for i := range chunk {
+		data, segment := self.point(start + uint64(i))
+		if err := segment.encoding.DecodeG1(&chunk[i], data); err != nil {
+			return nil, &FileError{segment.fileName, fmt.Errorf("%w: point %d: %v", ErrInvalidPoint, start+uint64(i), err)}
+		}
+	}
+	self.cache.put(k, chunk)
+	return chunk, nil
+}
+
+func (self *mappedComponent) g2Chunk(k uint64) ([]mcl.G2, error) {
+	if chunk, ok := self.cache.get(k); ok {
+		return chunk.([]mcl.G2), nil
+	}
+	start := k * self.chunkSize
+	stop := minUint64(start+self.chunkSize, self.num)
+	chunk := make([]mcl.G2, stop-start)
+	for i := range chunk {
+		data, segment := self.point(start + uint64(i))
+		if err := segment.encoding.DecodeG2(&chunk[i], data); err != nil {
+			return nil, &FileError{segment.fileName, fmt.Errorf("%w: point %d: %v", ErrInvalidPoint, start+uint64(i), err)}
+		}
+	}
+	self.cache.put(k, chunk)
+	return chunk, nil
+}
+
+func (self *mappedComponent) g1Range(start uint64, stop uint64) ([]mcl.G1, error) {
+	self.checkRange(start, stop)
+	out := make([]mcl.G1, 0, stop-start)
+	for i := start; i < stop; {
+		k := i / self.chunkSize
+		chunk, err := self.g1Chunk(k)
+		if err != nil {
+			return nil, err
+		}
+		hi := minUint64(stop, (k+1)*self.chunkSize)
+		out = append(out, chunk[i-k*self.chunkSize:hi-k*self.chunkSize]...)
+		i = hi
+	}
+	return out, nil
+}
+
+func (self *mappedComponent) g2Range(start uint64, stop uint64) ([]mcl.G2, error) {
+	self.checkRange(start, stop)
+	out := make([]mcl.G2, 0, stop-start)
+	for i := start; i < stop; {
+		k := i / self.chunkSize
+		chunk, err := self.g2Chunk(k)
+		if err != nil {
+			return nil, err
+		}
+		hi := minUint64(stop, (k+1)*self.chunkSize)
+		out = append(out, chunk[i-k*self.chunkSize:hi-k*self.chunkSize]...)
+		i = hi
+	}
+	return out, nil
+}
+
+// LRU of decoded chunks, safe for concurrent use.
+type chunkCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front is the most recently used
+	items    map[uint64]*list.Element
+}
+
+type chunkEntry struct {
+	k     uint64
+	chunk interface{}
+}
+
+func newChunkCache(capacity int) *chunkCache {
+	return &chunkCache{capacity: capacity, order: list.New(), items: make(map[uint64]*list.Element)}
+}
+
+func (self *chunkCache) get(k uint64) (interface{}, bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if e, ok := self.items[k]; ok {
+		self.order.MoveToFront(e)
+		return e.Value.(*chunkEntry).chunk, true
+	}
+	return nil, false
+}
+
+func (self *chunkCache) put(k uint64, chunk interface{}) {
+	if self.capacity <= 0 {
+		return
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if e, ok := self.items[k]; ok {
+		self.order.MoveToFront(e)
+		return
+	}
+	self.items[k] = self.order.PushFront(&chunkEntry{k, chunk})
+	for self.order.Len() > self.capacity {
+		e := self.order.Back()
+		self.order.Remove(e)
+		delete(self.items, e.Value.(*chunkEntry).k)
+	}
+}
+
+// Accessors of the powers, for both the in-memory and the mapped storage.
+// The returned slices must not be modified. The in-memory storage never returns an error, while the mapped one
+// returns an ErrInvalidPoint error for a bad point. A range out of the powers panics, like slicing.
+
+func (self *BpAcc) PKRange(start uint64, stop uint64) ([]mcl.G1, error) {
+	if self.store != nil {
+		return self.store.PK.g1Range(start, stop)
+	}
+	return self.PK[start-self.pkStart : stop-self.pkStart], nil
+}
+
+func (self *BpAcc) VKRange(start uint64, stop uint64) ([]mcl.G2, error) {
+	if self.store != nil {
+		return self.store.VK.g2Range(start, stop)
+	}
+	return self.VK[start:stop], nil
+}
+
+func (self *BpAcc) VKAlphaRange(start uint64, stop uint64) ([]mcl.G2, error) {
+	if self.store != nil {
+		return self.store.VKAlpha.g2Range(start, stop)
+	}
+	return self.VKAlpha[start:stop], nil
+}
+
+func (self *BpAcc) PedVKRange(start uint64, stop uint64) ([]mcl.G2, error) {
+	if self.store != nil {
+		return self.store.PedVK.g2Range(start, stop)
+	}
+	return self.PedVK[start:stop], nil
+}
+
+func (self *BpAcc) PedVKAlphaRange(start uint64, stop uint64) ([]mcl.G2, error) {
+	if self.store != nil {
+		return self.store.PedVKAlpha.g2Range(start, stop)
+	}
+	return self.PedVKAlpha[start:stop], nil
+}
+
+// Single powers
+
+func (self *BpAcc) PKAt(i uint64) (mcl.G1, error) {
+	pk, err := self.PKRange(i, i+1)
+	if err != nil {
+		return mcl.G1{}, err
+	}
+	return pk[0], nil
+}
+
+func (self *BpAcc) VKAt(i uint64) (mcl.G2, error) {
+	vk, err := self.VKRange(i, i+1)
+	if err != nil {
+		return mcl.G2{}, err
+	}
+	return vk[0], nil
+}
+
+func (self *BpAcc) PedVKAt(i uint64) (mcl.G2, error) {
+	vk, err := self.PedVKRange(i, i+1)
+	if err != nil {
+		return mcl.G2{}, err
+	}
+	return vk[0], nil
+}
+
+func (self *BpAcc) PedVKAlphaAt(i uint64) (mcl.G2, error) {
+	vk, err := self.PedVKAlphaRange(i, i+1)
+	if err != nil {
+		return mcl.G2{}, err
+	}
+	return vk[0], nil
+}
+
+// Same as PKRange, VKRange, ..., but a bad point panics, for the core code that does not return errors.
+
+func (self *BpAcc) pkRange(start uint64, stop uint64) []mcl.G1 {
+	pk, err := self.PKRange(start, stop)
+	check(err)
+	return pk
+}
+
+func (self *BpAcc) vkRange(start uint64, stop uint64) []mcl.G2 {
+	vk, err := self.VKRange(start, stop)
+	check(err)
+	return vk
+}
+
+func (self *BpAcc) vkAlphaRange(start uint64, stop uint64) []mcl.G2 {
+	vk, err := self.VKAlphaRange(start, stop)
+	check(err)
+	return vk
+}
+
+func (self *BpAcc) pedVKRange(start uint64, stop uint64) []mcl.G2 {
+	vk, err := self.PedVKRange(start, stop)
+	check(err)
+	return vk
+}
+
+func (self *BpAcc) pedVKAlphaRange(start uint64, stop uint64) []mcl.G2 {
+	vk, err := self.PedVKAlphaRange(start, stop)
+	check(err)
+	return vk
+}
+
+func (self *BpAcc) pkAt(i uint64) mcl.G1 {
+	return self.pkRange(i, i+1)[0]
+}
+
+func (self *BpAcc) vkAt(i uint64) mcl.G2 {
+	return self.vkRange(i, i+1)[0]
+}
+
+func (self *BpAcc) pedVKAt(i uint64) mcl.G2 {
+	return self.pedVKRange(i, i+1)[0]
+}
+
+func (self *BpAcc) pedVKAlphaAt(i uint64) mcl.G2 {
+	return self.pedVKAlphaRange(i, i+1)[0]
+}
+
+// All the powers, decoded from the mappings for the mapped storage.
+// Only meant for the checks of the whole parameters, which need them all anyway.
+func (self *BpAcc) allPowers() ([]mcl.G1, []mcl.G2, []mcl.G2, []mcl.G2, []mcl.G2, error) {
+	if self.store == nil {
+		return self.PK, self.VK, self.VKAlpha, self.PedVK, self.PedVKAlpha, nil
+	}
+	num := self.Q + 1
+	PK, err := self.PKRange(0, num)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	VK := make([][]mcl.G2, 4)
+	for i, vkRange := range []func(uint64, uint64) ([]mcl.G2, error){self.VKRange, self.VKAlphaRange, self.PedVKRange, self.PedVKAlphaRange} {
+		if VK[i], err = vkRange(0, num); err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+	}
+	return PK, VK[0], VK[1], VK[2], VK[3], nil
+}
+
+// The mappings are read-only, thus the operations rewriting the powers need them in memory.
+func (self *BpAcc) needsInMemory(operation string) error {
+	if self.store != nil {
+		return fmt.Errorf("%s rewrites the powers, which KeyGenLoadMapped does not support. Load the folder in memory.", operation)
+	}
+	return nil
+}
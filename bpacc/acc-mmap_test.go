@@ -0,0 +1,152 @@
+package bpacc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestKeyGenLoadMapped(t *testing.T) {
+
+	folder := t.TempDir()
+	var acc BpAcc
+	acc.KeyGen(8, 6, "xyz", folder)
+
+	var mapped BpAcc
+	mapped.KeyGenLoadMapped(8, 5, folder, 2)
+	defer mapped.Close()
+
+	if mapped.PK != nil || mapped.VK != nil {
+		t.Fatalf("Mapped accumulator allocated the powers")
+	}
+
+	// Small chunks, so that ranges cross chunks and segments
+	store := mapped.store
+	for _, c := range []*mappedComponent{&store.PK, &store.VK, &store.VKAlpha, &store.PedVK, &store.PedVKAlpha} {
+		c.chunkSize = 5
+	}
+
+	for _, r := range [][2]uint64{{0, 1}, {3, 30}, {0, 33}, {32, 33}, {7, 7}} {
+		pk, err := mapped.PKRange(r[0], r[1])
+		if err != nil || len(pk) != int(r[1]-r[0]) {
+			t.Fatalf("PKRange%v returned %d points: %v", r, len(pk), err)
+		}
+		for i := range pk {
+			if !pk[i].IsEqual(&acc.PK[r[0]+uint64(i)]) {
+				t.Fatalf("PKRange%v differs at %d", r, i)
+			}
+		}
+		g2 := []struct {
+			mapped []mcl.G2
+			loaded []mcl.G2
+		}{
+			{mapped.vkRange(r[0], r[1]), acc.VK[r[0]:r[1]]},
+			{mapped.vkAlphaRange(r[0], r[1]), acc.VKAlpha[r[0]:r[1]]},
+			{mapped.pedVKRange(r[0], r[1]), acc.PedVK[r[0]:r[1]]},
+			{mapped.pedVKAlphaRange(r[0], r[1]), acc.PedVKAlpha[r[0]:r[1]]},
+		}
+		for k := range g2 {
+			for i := range g2[k].loaded {
+				if !g2[k].mapped[i].IsEqual(&g2[k].loaded[i]) {
+					t.Fatalf("Component %d, range %v differs at %d", k, r, i)
+				}
+			}
+		}
+	}
+	if store.PK.cache.order.Len() > 2 {
+		t.Errorf("Cache holds %d chunks", store.PK.cache.order.Len())
+	}
+
+	// Proving and verifying off the mapped files
+	X := PopulateRandom(20)
+	I := PopulateRandom(4)
+	digest, _ := mapped.Commit(append(X, I...))
+	proofs := mapped.MemProve(X, I)
+	for i := range I {
+		if !mapped.MemVerifySingle(digest, I[i], proofs[i]) {
+			t.Errorf("Membership proof %d did not verify", i)
+		}
+	}
+	reference, _ := acc.Commit(append(X, I...))
+	if !digest.IsEqual(&reference) {
+		t.Errorf("Digests differ")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Range past the loaded ELL accepted")
+		}
+	}()
+	mapped.PKRange(0, mapped.Q+2)
+}
+
+// The checks of the whole parameters read the mapped powers, not the nil slices.
+func TestMappedParamsChecks(t *testing.T) {
+
+	folder := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "manager.data")
+	var manager BpAcc
+	manager.KeyGenPublic(8, 4, folder, secret)
+
+	var mapped BpAcc
+	mapped.KeyGenLoadMapped(8, 4, folder, 1)
+	defer mapped.Close()
+
+	if err := mapped.VerifyParams(); err != nil {
+		t.Errorf("Mapped parameters did not verify: %s", err)
+	}
+	if err := mapped.CheckGenerators(GENERATORS_LABEL); err != nil {
+		t.Errorf("Mapped generators did not check: %s", err)
+	}
+	if err := mapped.CheckGenerators("some-other-label"); err == nil {
+		t.Errorf("Mapped generators checked under a different label")
+	}
+	mapped.LoadManagerSecret(secret)
+	if !mapped.HasTrapdoor() || !mapped.IsParamsCorrect() {
+		t.Errorf("Manager secret does not match the mapped parameters.")
+	}
+
+	if _, err := mapped.Contribute(); err == nil {
+		t.Errorf("Contribution to the mapped powers accepted")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Rescale of the mapped powers accepted")
+		}
+	}()
+	var x mcl.Fr
+	x.SetInt64(2)
+	mapped.Rescale(x, x)
+}
+
+// Mapping only compares the sizes with the manifest; the hashes are left to VerifyManifest.
+func TestMappedManifest(t *testing.T) {
+
+	folder := t.TempDir()
+	acc := NewBpAcc(Options{Encoding: POINT_TRUSTED})
+	acc.KeyGen(8, 3, "xyz", folder)
+
+	fileName := folder + "/prk-00.data"
+	data, err := os.ReadFile(fileName)
+	check(err)
+	data[len(data)-G1_COMPRESSED_SIZE] ^= 1 // Low byte of the y of the last point
+	check(os.WriteFile(fileName, data, 0644))
+
+	trusting := NewBpAcc(Options{TrustedFolders: []string{folder}})
+	if err := trusting.TryKeyGenLoadMapped(8, 3, folder, 1); err != nil {
+		t.Errorf("Mapping hashed the files: %s", err)
+	}
+	if err := trusting.VerifyManifest(); !errors.Is(err, ErrFormat) {
+		t.Errorf("Changed file reported as: %v", err)
+	}
+	trusting.Close()
+
+	check(os.WriteFile(fileName, data[:len(data)-1], 0644))
+	if err := trusting.TryKeyGenLoadMapped(8, 3, folder, 1); !errors.Is(err, ErrShortRead) {
+		trusting.Close()
+		t.Errorf("Truncated file reported as: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package bpacc
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Segment files are numbered with two digits
+const MAX_SEGMENTS = 100
+
+// Settings of one accumulator, thus several accumulators with different settings can live in the same process.
+// The zero value gives the defaults.
+// (NOTE): The ncores argument of KeyGen, KeyGenLoad, etc. is still honored: when it is not zero, it sets Workers.
+type Options struct {
+	Workers  int         // Goroutines used by keygen, loading and the parameter checks. 0 means runtime.NumCPU().
+	Segments int         // Segment files per component written by keygen, at most MAX_SEGMENTS. 0 means NFILES.
+	Logger   *log.Logger // Progress messages. nil prints them to stdout.
+	Rand     io.Reader   // Randomness of the trapdoors, the ceremony and the proofs, safe for concurrent use. nil means the CSPRNG of mcl.
+
+	// Point encoding of the files written by this accumulator and of its proofs, see PointEncoding.
+	// The zero value is POINT_COMPRESSED.
+	Encoding PointEncoding
+
+	// Folders this operator produced itself: their POINT_TRUSTED files are decoded without any check.
+	// The files of any other folder are checked, whatever their header says.
+	TrustedFolders []string
+}
+
+var defaultLogger = log.New(os.Stdout, "", 0)
+
+func NewBpAcc(opts Options) *BpAcc {
+	return &BpAcc{opts: opts}
+}
+
+func (self *BpAcc) Options() Options {
+	return self.opts
+}
+
+func (self *BpAcc) SetOptions(opts Options) {
+	self.opts = opts
+}
+
+// Applies the ncores argument of the entry points.
+func (self *BpAcc) setWorkers(ncores uint8) {
+	if ncores != 0 {
+		self.opts.Workers = int(ncores)
+	}
+}
+
+func (self *BpAcc) workers() int {
+	if self.opts.Workers > 0 {
+		return self.opts.Workers
+	}
+	return runtime.NumCPU()
+}
+
+func (self *BpAcc) segments() (int, error) {
+	if self.opts.Segments == 0 {
+		return NFILES, nil
+	}
+	if self.opts.Segments < 0 || self.opts.Segments > MAX_SEGMENTS {
+		return 0, fmt.Errorf("%w: %d segments, wants 1 to %d", ErrFormat, self.opts.Segments, MAX_SEGMENTS)
+	}
+	return self.opts.Segments, nil
+}
+
+func (self *BpAcc) logln(v ...interface{}) {
+	if self.opts.Logger != nil {
+		self.opts.Logger.Println(v...)
+		return
+	}
+	defaultLogger.Println(v...)
+}
+
+// Samples x from Options.Rand. 64 bytes are reduced modulo r, thus the bias is negligible.
+func (self *BpAcc) randomFr(x *mcl.Fr) {
+	if self.opts.Rand == nil {
+		x.Random()
+		return
+	}
+	buf := make([]byte, 64)
+	_, err := io.ReadFull(self.opts.Rand, buf)
+	check(err)
+	check(x.SetLittleEndianMod(buf))
+	for i := range buf {
+		buf[i] = 0
+	}
+}
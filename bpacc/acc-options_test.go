@@ -0,0 +1,69 @@
+package bpacc
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+
+	// Different settings side by side
+	var logs [2]bytes.Buffer
+	folders := []string{t.TempDir(), t.TempDir()}
+	accs := []*BpAcc{
+		NewBpAcc(Options{Workers: 1, Segments: 4, Logger: log.New(&logs[0], "", 0)}),
+		NewBpAcc(Options{Workers: 3, Segments: 7, Logger: log.New(&logs[1], "", 0)}),
+	}
+	var wg sync.WaitGroup
+	for k := range accs {
+		k := k
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accs[k].KeyGen(0, 5, "xyz", folders[k])
+		}()
+	}
+	wg.Wait()
+
+	for k, segments := range []int{4, 7} {
+		files, _ := filepath.Glob(filepath.Join(folders[k], "prk-*.data"))
+		if len(files) != segments {
+			t.Errorf("Accumulator %d wrote %d segments, wants %d", k, len(files), segments)
+		}
+		if !strings.Contains(logs[k].String(), folders[k]) || strings.Contains(logs[k].String(), folders[1-k]) {
+			t.Errorf("Accumulator %d logged to the wrong place", k)
+		}
+		var loaded BpAcc
+		if err := loaded.TryKeyGenLoad(0, 5, "xyz", folders[k]); err != nil {
+			t.Fatalf("Accumulator %d did not load: %s", k, err)
+		}
+		if !loaded.PK[loaded.Q].IsEqual(&accs[k].PK[accs[k].Q]) {
+			t.Errorf("Accumulator %d was not loaded back", k)
+		}
+	}
+	if accs[0].Options().Workers != 1 {
+		t.Errorf("Workers changed by KeyGen with ncores 0")
+	}
+
+	// The same randomness gives the same trapdoors
+	var digests [2]BpAcc
+	for k := range digests {
+		digests[k].SetOptions(Options{Rand: bytes.NewReader(bytes.Repeat([]byte{7}, 1<<10)), Logger: log.New(&logs[0], "", 0)})
+		digests[k].KeyGenPublic(2, 3, t.TempDir(), "")
+	}
+	if !digests[0].PK[1].IsEqual(&digests[1].PK[1]) {
+		t.Errorf("Options.Rand was not used by KeyGenPublic")
+	}
+
+	for _, segments := range []int{-1, MAX_SEGMENTS + 1} {
+		acc := NewBpAcc(Options{Segments: segments, Logger: log.New(&logs[0], "", 0)})
+		if err := acc.TryKeyGen(2, 3, "xyz", t.TempDir()); !errors.Is(err, ErrFormat) {
+			t.Errorf("%d segments reported as: %v", segments, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package bpacc
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Public counterpart of IsParamsCorrect: it only needs pairings, not S or Alpha.
+// Every component is checked against a relation of the form e(a_i, X) = e(b_i, Y), e.g.
+// e(PK[i], h) = e(PK[i-1], VK[1]). Instead of checking each i, a random linear combination
+// of the whole range is checked at once, and the range is bisected only when it fails.
+
+type ParamsError struct {
+	Component string
+	Index     uint64
+	Reason    string
+}
+
+func (self *ParamsError) Error() string {
+	return fmt.Sprintf("%s error at index %d: %s", self.Component, self.Index, self.Reason)
+}
+
+// e(a_i, X) = e(b_i, Y) with a_i, b_i in G1
+type g1Relation struct {
+	component string
+	offset    uint64 // Index of a[0] in the component
+	a         []mcl.G1
+	b         []mcl.G1
+	X         mcl.G2
+	Y         mcl.G2
+}
+
+// e(X, a_i) = e(Y, b_i) with a_i, b_i in G2
+type g2Relation struct {
+	component string
+	offset    uint64
+	a         []mcl.G2
+	b         []mcl.G2
+	X         mcl.G1
+	Y         mcl.G1
+}
+
+// Returns nil if the parameters are well formed, otherwise the first failing component and index.
+func (self *BpAcc) VerifyParams() error {
+
+	if self.Q != uint64(1)<<self.ELL {
+		return &ParamsError{"Q", 0, fmt.Sprintf("Q vs ELL: %d vs %d", self.Q, self.ELL)}
+	}
+
+	PK, VK, VKAlpha, PedVK, PedVKAlpha, err := self.allPowers()
+	if err != nil {
+		return err
+	}
+	lengths := []struct {
+		name string
+		n    int
+	}{
+		{"PK", len(PK)}, {"VK", len(VK)}, {"VKAlpha", len(VKAlpha)},
+		{"PedVK", len(PedVK)}, {"PedVKAlpha", len(PedVKAlpha)},
+	}
+	for _, l := range lengths {
+		if uint64(l.n) != self.Q+1 {
+			return &ParamsError{l.name, 0, fmt.Sprintf("Q + 1 != len(%s): %d vs %d", l.name, self.Q+1, l.n)}
+		}
+	}
+	if len(self.PKAlpha) != 1 {
+		return &ParamsError{"PKAlpha", 0, fmt.Sprintf("len(PKAlpha) != 1: %d vs 1", len(self.PKAlpha))}
+	}
+
+	if self.G.IsZero() || !self.G.IsEqual(&PK[0]) {
+		return &ParamsError{"PK", 0, "PK[0] is not the generator G"}
+	}
+	if self.H.IsZero() || !self.H.IsEqual(&VK[0]) {
+		return &ParamsError{"VK", 0, "VK[0] is not the generator H"}
+	}
+	if self.PedH.IsZero() || !self.PedH.IsEqual(&PedVK[0]) {
+		return &ParamsError{"PedVK", 0, "PedVK[0] is not the generator PedH"}
+	}
+	if self.PKAlpha[0].IsZero() {
+		return &ParamsError{"PKAlpha", 0, "PKAlpha[0] is zero"}
+	}
+
+	for i := uint64(0); i <= self.Q; i++ {
+		if PK[i].IsZero() {
+			return &ParamsError{"PK", i, "zero element"}
+		}
+		if VK[i].IsZero() {
+			return &ParamsError{"VK", i, "zero element"}
+		}
+		if VKAlpha[i].IsZero() {
+			return &ParamsError{"VKAlpha", i, "zero element"}
+		}
+		if PedVK[i].IsZero() {
+			return &ParamsError{"PedVK", i, "zero element"}
+		}
+		if PedVKAlpha[i].IsZero() {
+			return &ParamsError{"PedVKAlpha", i, "zero element"}
+		}
+	}
+
+	// VK[1] is pinned down by PK[1] first, as the PK relation relies on it.
+	if !MultiPairing2(PK[1], self.H, self.G, VK[1]) {
+		return &ParamsError{"VK", 1, "e(PK[1], h) != e(g, VK[1])"}
+	}
+
+	Q := self.Q
+	// e(PK[i], h) = e(PK[i-1], h^s)
+	pkRelation := g1Relation{"PK", 1, PK[1:], PK[:Q], self.H, VK[1]}
+	if err := self.checkG1Relation(&pkRelation); err != nil {
+		return err
+	}
+
+	g2Relations := []g2Relation{
+		{"VK", 1, VK[1:], VK[:Q], self.G, PK[1]},                      // e(g, VK[i]) = e(g^s, VK[i-1])
+		{"VKAlpha", 0, VKAlpha, VK, self.G, self.PKAlpha[0]},          // e(g, VKAlpha[i]) = e(g^a, VK[i])
+		{"PedVK", 1, PedVK[1:], PedVK[:Q], self.G, PK[1]},             // e(g, PedVK[i]) = e(g^s, PedVK[i-1])
+		{"PedVKAlpha", 0, PedVKAlpha, PedVK, self.G, self.PKAlpha[0]}, // e(g, PedVKAlpha[i]) = e(g^a, PedVK[i])
+	}
+	for k := range g2Relations {
+		if err := self.checkG2Relation(&g2Relations[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *BpAcc) checkG1Relation(rel *g1Relation) error {
+	holds := func(lo uint64, hi uint64) bool {
+		A, B := self.randomCombinationG1(rel.a[lo:hi], rel.b[lo:hi])
+		return MultiPairing2(A, rel.X, B, rel.Y)
+	}
+	if i, failed := firstFailure(holds, 0, uint64(len(rel.a))); failed {
+		return &ParamsError{rel.component, rel.offset + i, "power relation does not hold"}
+	}
+	return nil
+}
+
+func (self *BpAcc) checkG2Relation(rel *g2Relation) error {
+	holds := func(lo uint64, hi uint64) bool {
+		A, B := self.randomCombinationG2(rel.a[lo:hi], rel.b[lo:hi])
+		return MultiPairing2(rel.X, A, rel.Y, B)
+	}
+	if i, failed := firstFailure(holds, 0, uint64(len(rel.a))); failed {
+		return &ParamsError{rel.component, rel.offset + i, "power relation does not hold"}
+	}
+	return nil
+}
+
+// Returns the smallest index in [lo, hi) where the relation fails, by bisecting the failing range.
+func firstFailure(holds func(uint64, uint64) bool, lo uint64, hi uint64) (uint64, bool) {
+	if lo >= hi || holds(lo, hi) {
+		return 0, false
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if !holds(lo, mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return lo, true
+}
+
+// Computes sum r_i a_i and sum r_i b_i for the same random r_i's, split across the workers.
+func (self *BpAcc) randomCombinationG1(a []mcl.G1, b []mcl.G1) (mcl.G1, mcl.G1) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var A, B mcl.G1
+
+	num := uint64(len(a))
+	step := uint64(math.Ceil(float64(num) / float64(self.workers())))
+	for start := uint64(0); start < num; start += step {
+		stop := minUint64(start+step, num)
+		wg.Add(1)
+		go func(start uint64, stop uint64) {
+			defer wg.Done()
+			r := make([]mcl.Fr, stop-start)
+			for i := range r {
+				self.randomFr(&r[i])
+			}
+			var x, y mcl.G1
+			mcl.G1MulVec(&x, a[start:stop], r)
+			mcl.G1MulVec(&y, b[start:stop], r)
+			mu.Lock()
+			mcl.G1Add(&A, &A, &x)
+			mcl.G1Add(&B, &B, &y)
+			mu.Unlock()
+		}(start, stop)
+	}
+	wg.Wait()
+	return A, B
+}
+
+func (self *BpAcc) randomCombinationG2(a []mcl.G2, b []mcl.G2) (mcl.G2, mcl.G2) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var A, B mcl.G2
+
+	num := uint64(len(a))
+	step := uint64(math.Ceil(float64(num) / float64(self.workers())))
+	for start := uint64(0); start < num; start += step {
+		stop := minUint64(start+step, num)
+		wg.Add(1)
+		go func(start uint64, stop uint64) {
+			defer wg.Done()
+			r := make([]mcl.Fr, stop-start)
+			for i := range r {
+				self.randomFr(&r[i])
+			}
+			var x, y mcl.G2
+			mcl.G2MulVec(&x, a[start:stop], r)
+			mcl.G2MulVec(&y, b[start:stop], r)
+			mu.Lock()
+			mcl.G2Add(&A, &A, &x)
+			mcl.G2Add(&B, &B, &y)
+			mu.Unlock()
+		}(start, stop)
+	}
+	wg.Wait()
+	return A, B
+}
@@ -0,0 +1,52 @@
+package bpacc
+
+import (
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestVerifyParams(t *testing.T) {
+
+	l := uint64(6)
+	var acc BpAcc
+	acc.KeyGenPublic(8, l, t.TempDir(), "")
+
+	if err := acc.VerifyParams(); err != nil {
+		t.Fatalf("Correct parameters did not verify: %s", err)
+	}
+
+	cases := []struct {
+		component string
+		index     uint64
+		target    *mcl.G2
+		value     mcl.G2
+	}{
+		{"VK", 12, &acc.VK[12], acc.VK[13]},
+		{"VKAlpha", 0, &acc.VKAlpha[0], acc.VK[0]},
+		{"PedVK", 64, &acc.PedVK[64], acc.VK[64]},
+		{"PedVKAlpha", 9, &acc.PedVKAlpha[9], acc.PedVK[9]},
+	}
+
+	for _, c := range cases {
+		original := *c.target
+		*c.target = c.value
+		err := acc.VerifyParams()
+		*c.target = original
+
+		perr, ok := err.(*ParamsError)
+		if !ok || perr.Component != c.component || perr.Index != c.index {
+			t.Errorf("Tampered %s[%d] reported as: %v", c.component, c.index, err)
+		}
+	}
+
+	original := acc.PK[37]
+	acc.PK[37] = acc.PK[36]
+	err := acc.VerifyParams()
+	acc.PK[37] = original
+
+	perr, ok := err.(*ParamsError)
+	if !ok || perr.Component != "PK" || perr.Index != 37 {
+		t.Errorf("Tampered PK[37] reported as: %v", err)
+	}
+}
@@ -11,14 +11,14 @@ import (
 // But proving in G2 implies, two G1 elements as proof.
 func (self *BpAcc) NiPoEProveG1(w mcl.G1, u mcl.G1, v []mcl.Fr) (mcl.G1, mcl.G2) {
 
-	ell := HashPoEParamsG1(w, u, v)
+	ell := HashPoEParamsG1(w, u, v, self.opts.Encoding)
 	q, r := fft.PolyDiv(v, ell)
 	p := fft.PolySub(v, r)
 
 	var Q1 mcl.G1
 	var Q2 mcl.G2
-	mcl.G1MulVec(&Q1, self.PK[:len(q)], q)
-	mcl.G2MulVec(&Q2, self.VK[:len(p)], p)
+	mcl.G1MulVec(&Q1, self.pkRange(0, uint64(len(q))), q)
+	mcl.G2MulVec(&Q2, self.vkRange(0, uint64(len(p))), p)
 
 	return Q1, Q2
 }
@@ -26,11 +26,11 @@ func (self *BpAcc) NiPoEProveG1(w mcl.G1, u mcl.G1, v []mcl.Fr) (mcl.G1, mcl.G2)
 // Actual protocol is in the paper. Notice that proving in G1 implies one G1 and one G2 element as proof.
 // But proving in G2 implies, two G1 elements as proof.
 func (self *BpAcc) NiPoEVerifyG1(Q1 mcl.G1, Q2 mcl.G2, w mcl.G1, u mcl.G1, v []mcl.Fr) bool {
-	ell := HashPoEParamsG1(w, u, v)
+	ell := HashPoEParamsG1(w, u, v, self.opts.Encoding)
 	_, r := fft.PolyDiv(v, ell)
 
 	var h1 mcl.G2
-	mcl.G2MulVec(&h1, self.VK[:len(ell)], ell)
+	mcl.G2MulVec(&h1, self.vkRange(0, uint64(len(ell))), ell)
 	status1 := MultiPairing2(Q1, h1, self.G, Q2)
 	if !status1 {
 		return false
@@ -44,15 +44,16 @@ func (self *BpAcc) NiPoEVerifyG1(Q1 mcl.G1, Q2 mcl.G2, w mcl.G1, u mcl.G1, v []m
 }
 
 // Return (x - l)
-func HashPoEParamsG1(w mcl.G1, u mcl.G1, v []mcl.Fr) []mcl.Fr {
+// The points are hashed in encoding, the Options.Encoding of the prover and the verifier.
+func HashPoEParamsG1(w mcl.G1, u mcl.G1, v []mcl.Fr, encoding PointEncoding) []mcl.Fr {
 	N := len(v)
 	if N == 0 {
 		panic("PoE: V(s) polynomial is empty.")
 	}
-	total := GetG1ByteSize() + GetG1ByteSize() + len(v)*GetFrByteSize()
+	total := encoding.G1Size() + encoding.G1Size() + len(v)*GetFrByteSize()
 	input := make([]byte, 0, total)
-	input = append(input, w.Serialize()...)
-	input = append(input, u.Serialize()...)
+	input = append(input, encoding.EncodeG1(&w)...)
+	input = append(input, encoding.EncodeG1(&u)...)
 	for i := range v {
 		input = append(input, v[i].Serialize()...)
 	}
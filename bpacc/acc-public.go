@@ -0,0 +1,196 @@
+package bpacc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alinush/go-mcl"
+)
+
+// Same layout as SaveTrapdoor, but without S and Alpha.
+// Everything in this file is safe to hand out to provers and verifiers.
+func (self *BpAcc) SavePublic() {
+	check(self.savePublic())
+}
+
+func (self *BpAcc) savePublic() error {
+
+	fileName := self.folderPath + PUBLICNAME
+	self.logln("Saving data to:", fileName)
+
+	os.MkdirAll(self.folderPath, os.ModePerm)
+	f, err := createFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Report the size.
+	header := self.newFileHeader(PUBLIC_COMPONENT, 0, 0, 0)
+	if err := WriteHeader(f, header); err != nil {
+		return &FileError{fileName, err}
+	}
+	w := pointWriter{w: f, path: fileName, encoding: PointEncoding(header.Encoding)}
+
+	w.G1(&self.G)
+	w.G2(&self.H)
+
+	w.G1(&self.Gneg)
+	w.G2(&self.Hneg)
+
+	w.Write(self.IdGT.Serialize())
+	w.Write(self.InvIdGT.Serialize())
+
+	w.G2(&self.PedH)
+
+	for i := range self.A {
+		w.G1(&self.A[i])
+	}
+
+	for i := range self.B {
+		w.G2(&self.B[i])
+	}
+
+	w.G1(&self.PKAlpha[0])
+
+	return w.Err()
+}
+
+func (self *BpAcc) LoadPublic(L uint64) {
+	check(self.loadPublic(L))
+}
+
+func (self *BpAcc) loadPublic(L uint64) error {
+
+	fileName := self.folderPath + PUBLICNAME
+	f, err := openFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	self.logln(fileName)
+	header, err := ReadHeader(f)
+	if err == nil {
+		err = header.Expect(PUBLIC_COMPONENT, 0)
+	}
+	if err != nil {
+		return &FileError{fileName, err}
+	}
+	reportedEll := header.ELL
+
+	if reportedEll < L {
+		return &FileError{fileName, fmt.Errorf("%w: There is not enough to read! Found: %d, Wants: %d", ErrEllMismatch, reportedEll, L)}
+	}
+
+	r := pointReader{r: f, path: fileName, encoding: self.reading(PointEncoding(header.Encoding))}
+	r.G1(&self.G)
+	r.G2(&self.H)
+
+	r.G1(&self.Gneg)
+	r.G2(&self.Hneg)
+
+	r.GT(&self.IdGT)
+	r.GT(&self.InvIdGT)
+
+	r.G2(&self.PedH)
+
+	for i := range self.A {
+		r.G1(&self.A[i])
+	}
+
+	for i := range self.B {
+		r.G2(&self.B[i])
+	}
+
+	r.G1(&self.PKAlpha[0])
+
+	return r.Err()
+}
+
+// Writes the manager trapdoor (ELL, S and Alpha) to path.
+// path is expected to live outside the parameter folder.
+func (self *BpAcc) SaveManagerSecret(path string) {
+	check(self.saveManagerSecret(path))
+}
+
+func (self *BpAcc) saveManagerSecret(path string) error {
+
+	self.logln("Saving manager secret to:", path)
+
+	os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return &FileError{path, err}
+	}
+	defer f.Close()
+
+	header := self.newFileHeader(SECRET_COMPONENT, 0, 0, 0)
+	if err := WriteHeader(f, header); err != nil {
+		return &FileError{path, err}
+	}
+
+	w := pointWriter{w: f, path: path}
+	w.Write(self.S.Serialize())
+	w.Write(self.Alpha.Serialize())
+	return w.Err()
+}
+
+// Reads the manager trapdoor written by SaveManagerSecret.
+// The public parameters must be loaded first, as the trapdoor is checked against PK[1] and PKAlpha[0].
+// The trapdoor does not depend on ELL, thus the file is read at any ELL (and TryExtend leaves it alone).
+func (self *BpAcc) LoadManagerSecret(path string) {
+	check(self.loadManagerSecret(path))
+}
+
+func (self *BpAcc) loadManagerSecret(path string) error {
+
+	f, err := openFile(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header, err := ReadHeader(f)
+	if err == nil {
+		err = header.Expect(SECRET_COMPONENT, 0)
+	}
+	if err != nil {
+		return &FileError{path, err}
+	}
+	r := pointReader{r: f, path: path}
+	r.Fr(&self.S)
+	r.Fr(&self.Alpha)
+	if err := r.Err(); err != nil {
+		self.ClearTrapdoors()
+		return err
+	}
+
+	var gS, gAlpha mcl.G1
+	mcl.G1Mul(&gS, &self.G, &self.S)
+	mcl.G1Mul(&gAlpha, &self.G, &self.Alpha)
+	pk1, err := self.PKAt(1)
+	if err != nil {
+		self.ClearTrapdoors()
+		return err
+	}
+	if !gS.IsEqual(&pk1) || !gAlpha.IsEqual(&self.PKAlpha[0]) {
+		self.ClearTrapdoors()
+		return &FileError{path, errors.New("Manager secret does not match the public parameters.")}
+	}
+	return nil
+}
+
+// Overwrites S and Alpha with zeros.
+// Copies made by the Go runtime (e.g. goroutine stacks) are out of our reach.
+func (self *BpAcc) ClearTrapdoors() {
+	self.S.Clear()
+	self.Alpha.Clear()
+}
+
+// Returns true when the trapdoor is present in memory.
+func (self *BpAcc) HasTrapdoor() bool {
+	return !self.S.IsZero()
+}
@@ -0,0 +1,48 @@
+package bpacc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyGenPublic(t *testing.T) {
+
+	l := uint64(5)
+	folder := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "manager.data")
+
+	var manager BpAcc
+	manager.KeyGenPublic(8, l, folder, secret)
+	if manager.HasTrapdoor() || !manager.Alpha.IsZero() {
+		t.Errorf("Trapdoors were not cleared after key generation.")
+	}
+
+	if _, err := os.Stat(folder + TRAPDOORNAME); !os.IsNotExist(err) {
+		t.Errorf("Trapdoor file found in the public folder.")
+	}
+
+	var acc BpAcc
+	acc.KeyGenLoadPublic(8, l, folder)
+	if acc.HasTrapdoor() {
+		t.Errorf("Public loader should not have a trapdoor.")
+	}
+
+	n := uint64(1 << 4)
+	elements := PopulateRandom(n)
+	digest, _ := acc.Commit(elements)
+
+	X, I := elements[:n/2], elements[n/2:]
+	proofs := acc.MemProve(X, I)
+	for k := range I {
+		if !acc.MemVerifySingle(digest, I[k], proofs[k]) {
+			t.Errorf("Proof did not verify %d", k)
+			break
+		}
+	}
+
+	acc.LoadManagerSecret(secret)
+	if !acc.HasTrapdoor() || !acc.IsParamsCorrect() {
+		t.Errorf("Manager secret does not match the public parameters.")
+	}
+}
@@ -1,166 +1,142 @@
 package bpacc
 
 import (
-	"encoding/binary"
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/alinush/go-mcl"
 )
 
 func (self *BpAcc) SaveTrapdoor() {
+	check(self.saveTrapdoor())
+}
+
+func (self *BpAcc) saveTrapdoor() error {
 
-	fmt.Println("Saving data to:", self.folderPath+TRAPDOORNAME)
+	fileName := self.folderPath + TRAPDOORNAME
+	self.logln("Saving data to:", fileName)
 
 	os.MkdirAll(self.folderPath, os.ModePerm)
-	f, err := os.Create(self.folderPath + TRAPDOORNAME)
-	check(err)
+	f, err := createFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
 	// Report the size.
-	LBytes := make([]byte, 8) // Enough space for 64 bits of interger
-	binary.LittleEndian.PutUint64(LBytes, uint64(self.ELL))
-	_, err = f.Write(LBytes)
-	check(err)
+	header := self.newFileHeader(TRAPDOOR_COMPONENT, 0, 0, 0)
+	if err := WriteHeader(f, header); err != nil {
+		return &FileError{fileName, err}
+	}
+	w := pointWriter{w: f, path: fileName, encoding: PointEncoding(header.Encoding)}
 
 	// Write the trapdoor
-	_, err = f.Write(self.S.Serialize())
-	check(err)
+	w.Write(self.S.Serialize())
 
 	// Write the Generator to the file
-	_, err = f.Write(self.G.Serialize())
-	check(err)
-	_, err = f.Write(self.H.Serialize())
-	check(err)
+	w.G1(&self.G)
+	w.G2(&self.H)
 
-	_, err = f.Write(self.Gneg.Serialize())
-	check(err)
-	_, err = f.Write(self.Hneg.Serialize())
-	check(err)
+	w.G1(&self.Gneg)
+	w.G2(&self.Hneg)
 
-	_, err = f.Write(self.IdGT.Serialize())
-	check(err)
-	_, err = f.Write(self.InvIdGT.Serialize())
-	check(err)
+	w.Write(self.IdGT.Serialize())
+	w.Write(self.InvIdGT.Serialize())
 
 	// Write the KEA
-	_, err = f.Write(self.Alpha.Serialize())
-	check(err)
+	w.Write(self.Alpha.Serialize())
 
 	// Write Ped generator
-	_, err = f.Write(self.PedH.Serialize())
-	check(err)
+	w.G2(&self.PedH)
 
 	for i := range self.A {
-		_, err = f.Write(self.A[i].Serialize())
-		check(err)
+		w.G1(&self.A[i])
 	}
 
 	for i := range self.B {
-		_, err = f.Write(self.B[i].Serialize())
-		check(err)
+		w.G2(&self.B[i])
 	}
 
 	// Lastly, write the PKAlpha, as it is just one value.
 	// When/If PKAlpha is of size Q+1, then it will be saved and loaded along with VRK
-	_, err = f.Write(self.PKAlpha[0].Serialize())
-	check(err)
+	w.G1(&self.PKAlpha[0])
 
-	defer f.Close()
+	return w.Err()
 }
 
 func (self *BpAcc) LoadTrapdoor(L uint64) {
+	check(self.loadTrapdoor(L))
+}
 
-	fileName := self.folderPath + TRAPDOORNAME
-	f, err := os.Open(self.folderPath + TRAPDOORNAME)
-	check(err)
+func (self *BpAcc) loadTrapdoor(L uint64) error {
 
-	var data []byte
-	data = make([]byte, 8)
+	fileName := self.folderPath + TRAPDOORNAME
+	f, err := openFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-	fmt.Println(fileName)
-	_, err = f.Read(data)
-	check(err)
-	reportedEll := binary.LittleEndian.Uint64(data)
+	self.logln(fileName)
+	header, err := ReadHeader(f)
+	if err == nil {
+		err = header.Expect(TRAPDOOR_COMPONENT, 0)
+	}
+	if err != nil {
+		return &FileError{fileName, err}
+	}
+	reportedEll := header.ELL
 
 	if reportedEll < L {
-		// Assumes SaveTrapdoor is honest
-		panic(fmt.Sprintf("There is not enough to read! Found: %d, Wants: %d", L, reportedEll))
+		// Assumes SaveTrapdoor is honest. The segments are checked against their own headers.
+		return &FileError{fileName, fmt.Errorf("%w: There is not enough to read! Found: %d, Wants: %d", ErrEllMismatch, reportedEll, L)}
 	}
 
-	data = make([]byte, GetFrByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.S.Deserialize(data)
+	r := pointReader{r: f, path: fileName, encoding: self.reading(PointEncoding(header.Encoding))}
+	r.Fr(&self.S)
 
-	data = make([]byte, GetG1ByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.G.Deserialize(data)
+	r.G1(&self.G)
+	r.G2(&self.H)
 
-	data = make([]byte, GetG2ByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.H.Deserialize(data)
+	r.G1(&self.Gneg)
+	r.G2(&self.Hneg)
 
-	data = make([]byte, GetG1ByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.Gneg.Deserialize(data)
-
-	data = make([]byte, GetG2ByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.Hneg.Deserialize(data)
-
-	data = make([]byte, GetGTByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.IdGT.Deserialize(data)
-
-	data = make([]byte, GetGTByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.InvIdGT.Deserialize(data)
+	r.GT(&self.IdGT)
+	r.GT(&self.InvIdGT)
 
 	// Read the KEA
-	data = make([]byte, GetFrByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.Alpha.Deserialize(data)
+	r.Fr(&self.Alpha)
 
 	// Load Ped generator
-	data = make([]byte, GetG2ByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.PedH.Deserialize(data)
+	r.G2(&self.PedH)
 
 	for i := range self.A {
-		data = make([]byte, GetG1ByteSize())
-		_, err = f.Read(data)
-		check(err)
-		self.A[i].Deserialize(data)
+		r.G1(&self.A[i])
 	}
 
 	for i := range self.B {
-		data = make([]byte, GetG2ByteSize())
-		_, err = f.Read(data)
-		check(err)
-		self.B[i].Deserialize(data)
+		r.G2(&self.B[i])
 	}
 
-	data = make([]byte, GetG1ByteSize())
-	_, err = f.Read(data)
-	check(err)
-	self.PKAlpha[0].Deserialize(data)
+	r.G1(&self.PKAlpha[0])
 
-	f.Close()
+	return r.Err()
 }
 
+// Computes the powers in [start, stop) with one full multiplication per power and writes them to the index-th
+// segment of every component. This is how KeyGen computed the powers before the fixed-base tables of prkVrkGen.
 func (self *BpAcc) PrkVrkParallel(
 	index uint8, start uint64, stop uint64, wg *sync.WaitGroup) {
+	defer wg.Done()
+	check(self.prkVrkSegment(index, start, stop))
+}
+
+func (self *BpAcc) prkVrkSegment(index uint8, start uint64, stop uint64) error {
 
 	os.MkdirAll(self.folderPath, os.ModePerm)
 	fileNamePK := self.folderPath + fmt.Sprintf(PRK_NAME, index)
@@ -169,21 +145,13 @@ func (self *BpAcc) PrkVrkParallel(
 	fileNameVKAlpha := self.folderPath + fmt.Sprintf(VRK_KEA_NAME, index)
 	fileNamePedVK := self.folderPath + fmt.Sprintf(PED_VRK_NAME, index)
 	fileNamePedVKAlpha := self.folderPath + fmt.Sprintf(PED_VRK_KEA_NAME, index)
-	// const PED_NAME = "/ped-vrk-%02d.data"
-	// const PED_ALPHA_NAME = "/kea-ped-vrk-%02d.data"
-
-	fmt.Println("Saving data to:", fileNamePK, fileNameVK, fileNameVKAlpha, fileNamePedVK, fileNamePedVKAlpha)
-	f1, err := os.Create(fileNamePK)
-	check(err)
-	f2, err := os.Create(fileNameVK)
-	check(err)
-
-	f3, err := os.Create(fileNameVKAlpha)
-	check(err)
-	f4, err := os.Create(fileNamePedVK)
-	check(err)
-	f5, err := os.Create(fileNamePedVKAlpha)
-	check(err)
+
+	self.logln("Saving data to:", fileNamePK, fileNameVK, fileNameVKAlpha, fileNamePedVK, fileNamePedVKAlpha)
+	files, w, err := self.createSegments(index, start, stop)
+	if err != nil {
+		return err
+	}
+	defer closeAll(files)
 
 	a := FrPow(self.S, int64(start))
 	var gTmp mcl.G1
@@ -194,12 +162,9 @@ func (self *BpAcc) PrkVrkParallel(
 		mcl.G2Mul(&hTmp, &self.H, &a)
 		mcl.G2Mul(&hAlphaTmp, &hTmp, &self.Alpha)
 
-		_, err = f1.Write(gTmp.Serialize())
-		check(err)
-		_, err = f2.Write(hTmp.Serialize())
-		check(err)
-		_, err = f3.Write(hAlphaTmp.Serialize())
-		check(err)
+		w[0].G1(&gTmp)
+		w[1].G2(&hTmp)
+		w[2].G2(&hAlphaTmp)
 
 		self.PK[i] = gTmp
 		self.VK[i] = hTmp
@@ -208,21 +173,235 @@ func (self *BpAcc) PrkVrkParallel(
 		mcl.G2Mul(&hTmp, &self.PedH, &a)
 		mcl.G2Mul(&hAlphaTmp, &hTmp, &self.Alpha)
 
-		_, err = f4.Write(hTmp.Serialize())
-		check(err)
-		_, err = f5.Write(hAlphaTmp.Serialize())
-		check(err)
+		w[3].G2(&hTmp)
+		w[4].G2(&hAlphaTmp)
 		self.PedVK[i] = hTmp
 		self.PedVKAlpha[i] = hAlphaTmp
 
 		mcl.FrMul(&a, &a, &self.S)
 	}
-	defer f1.Close()
-	defer f2.Close()
-	defer f3.Close()
-	defer f4.Close()
-	defer f5.Close()
-	defer wg.Done()
+	a.Clear()
+	return firstError(w)
+}
+
+// Writes the in-memory PK, VK, VKAlpha, PedVK and PedVKAlpha back to the segment files.
+// Uses the same split as PrkVrkGen.
+func (self *BpAcc) SaveSegments() {
+	check(self.saveSegments())
+}
+
+func (self *BpAcc) saveSegments() error {
+	return self.writeSegments(0, 0)
+}
+
+// Writes the powers [start, Q] to Options.Segments segment files, numbered from first.
+func (self *BpAcc) writeSegments(first int, start uint64) error {
+	segments, err := self.segments()
+	if err == nil && first+segments > MAX_SEGMENTS {
+		err = fmt.Errorf("%w: no room for %d more segments", ErrFormat, segments)
+	}
+	if err != nil {
+		return &FileError{self.folderPath, err}
+	}
+
+	var group errorGroup
+	group.SetLimit(self.workers())
+
+	num := self.Q + 1
+	step := uint64(math.Ceil(float64(num-start) / float64(segments)))
+	stop := minUint64(start+step, num)
+
+	for i := 0; i < segments; i++ {
+		index, lo, hi := uint8(first+i), start, stop
+		group.Go(func() error { return self.segmentsSave(index, lo, hi) })
+
+		start += step
+		stop += step
+		stop = minUint64(stop, num)
+	}
+	return group.Wait()
+}
+
+// Rewrites the segment files of the folder that hold powers from start on, keeping their numbers and ranges.
+// The files are taken from the manifest, thus the whole folder has to be loaded (see needsWholeFolder).
+func (self *BpAcc) rewriteSegments(start uint64) error {
+	manifest, err := self.LoadManifest()
+	if err != nil {
+		return err
+	}
+
+	var group errorGroup
+	group.SetLimit(self.workers())
+	for _, entry := range manifest.Files {
+		if entry.Component != componentName(PRK_NAME) || entry.Stop <= start || entry.Start == entry.Stop {
+			continue
+		}
+		index, lo, hi := uint8(entry.Segment), entry.Start, entry.Stop
+		group.Go(func() error { return self.segmentsSave(index, lo, hi) })
+	}
+	return group.Wait()
+}
+
+// Operations that rewrite the folder in place need all of its powers.
+func (self *BpAcc) needsWholeFolder(operation string) error {
+	manifest, err := self.LoadManifest()
+	if err != nil {
+		return err
+	}
+	if manifest.ELL != self.ELL {
+		return &FileError{self.folderPath + MANIFESTNAME, fmt.Errorf("%w: %s needs the whole setup, loaded ELL %d of %d", ErrEllMismatch, operation, self.ELL, manifest.ELL)}
+	}
+	return nil
+}
+
+func (self *BpAcc) segmentsSave(index uint8, start uint64, stop uint64) error {
+
+	os.MkdirAll(self.folderPath, os.ModePerm)
+	fileNamePK := self.folderPath + fmt.Sprintf(PRK_NAME, index)
+	fileNameVK := self.folderPath + fmt.Sprintf(VRK_NAME, index)
+	fileNameVKAlpha := self.folderPath + fmt.Sprintf(VRK_KEA_NAME, index)
+	fileNamePedVK := self.folderPath + fmt.Sprintf(PED_VRK_NAME, index)
+	fileNamePedVKAlpha := self.folderPath + fmt.Sprintf(PED_VRK_KEA_NAME, index)
+
+	self.logln("Saving data to:", fileNamePK, fileNameVK, fileNameVKAlpha, fileNamePedVK, fileNamePedVKAlpha)
+	files, w, err := self.createSegments(index, start, stop)
+	if err != nil {
+		return err
+	}
+	defer closeAll(files)
+
+	for i := start; i < stop; i++ {
+		w[0].G1(&self.PK[i])
+		w[1].G2(&self.VK[i])
+		w[2].G2(&self.VKAlpha[i])
+		w[3].G2(&self.PedVK[i])
+		w[4].G2(&self.PedVKAlpha[i])
+	}
+	return firstError(w)
+}
+
+// Creates the index-th segment file of every component (in the order of SEGMENT_NAMES) and writes the headers.
+// Segments past the last power are empty, their range is [stop, stop).
+func (self *BpAcc) createSegments(index uint8, start uint64, stop uint64) ([]*os.File, []*pointWriter, error) {
+	start = minUint64(start, stop)
+	files := make([]*os.File, 0, len(SEGMENT_NAMES))
+	w := make([]*pointWriter, 0, len(SEGMENT_NAMES))
+	for _, pattern := range SEGMENT_NAMES {
+		fileName := self.folderPath + fmt.Sprintf(pattern, index)
+		f, err := createFile(fileName)
+		if err != nil {
+			closeAll(files)
+			return nil, nil, err
+		}
+		files = append(files, f)
+		header := self.newFileHeader(componentName(pattern), uint32(index), start, stop)
+		w = append(w, &pointWriter{w: f, path: fileName, encoding: PointEncoding(header.Encoding)})
+		if err := WriteHeader(f, header); err != nil {
+			closeAll(files)
+			return nil, nil, &FileError{fileName, err}
+		}
+	}
+	return files, w, nil
+}
+
+func closeAll(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+func firstError(w []*pointWriter) error {
+	for i := range w {
+		if err := w[i].Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Opens a segment file and checks that its header covers [start, stop).
+// Returns a reader of the points, right after the header.
+func (self *BpAcc) openSegment(fileName string, index uint8, start uint64, stop uint64) (*os.File, *pointReader, error) {
+	f, err := openFile(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := ReadHeader(f)
+	if err == nil {
+		err = header.Expect(segmentComponent(fileName), uint32(index))
+	}
+	if err == nil && (header.Start != start || header.Stop < stop) {
+		err = fmt.Errorf("%w: holds [%d, %d), wants [%d, %d)", ErrFormat, header.Start, header.Stop, start, stop)
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, &FileError{fileName, err}
+	}
+	return f, &pointReader{r: f, path: fileName, encoding: self.reading(PointEncoding(header.Encoding))}, nil
+}
+
+// The part of a segment file that has to be read.
+type segmentPart struct {
+	fileName string
+	index    uint8
+	start    uint64
+	stop     uint64
+	encoding PointEncoding
+}
+
+// Picks the segment files holding the first num elements of a component, using the ranges in their headers.
+// Thus a folder saved with a larger ELL loads any smaller ELL, whatever the split of the files.
+func segmentParts(files []string, num uint64) ([]segmentPart, error) {
+
+	headers := make([]FileHeader, len(files))
+	for i, fileName := range files {
+		header, err := readFileHeader(fileName)
+		if err != nil {
+			return nil, err
+		}
+		component := segmentComponent(fileName)
+		if err := header.Expect(component, header.Segment); err != nil {
+			return nil, &FileError{fileName, err}
+		}
+		if filepath.Base(fileName) != fmt.Sprintf(component+"-%02d.data", header.Segment) || header.Segment > math.MaxUint8 {
+			return nil, &FileError{fileName, fmt.Errorf("%w: header of segment %d", ErrFormat, header.Segment)}
+		}
+		headers[i] = header
+	}
+
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return headers[order[i]].Start < headers[order[j]].Start })
+
+	parts := make([]segmentPart, 0)
+	next := uint64(0)
+	for _, i := range order {
+		header := headers[i]
+		if next >= num {
+			break
+		}
+		if header.Start == header.Stop {
+			continue
+		}
+		if header.Start != next {
+			return nil, &FileError{files[i], fmt.Errorf("%w: no segment for [%d, %d)", ErrMissingFile, next, header.Start)}
+		}
+		stop := minUint64(header.Stop, num)
+		parts = append(parts, segmentPart{files[i], uint8(header.Segment), header.Start, stop, PointEncoding(header.Encoding)})
+		next = header.Stop
+	}
+	if next < num {
+		return nil, &FileError{filepath.Dir(files[0]), fmt.Errorf("%w: segments hold %d elements, wants %d", ErrEllMismatch, next, num)}
+	}
+	return parts, nil
+}
+
+// "/path/prk-03.data" -> "prk"
+func segmentComponent(fileName string) string {
+	base := filepath.Base(fileName)
+	return strings.TrimSuffix(base, base[strings.LastIndex(base, "-"):])
 }
 
 func (self *BpAcc) G1ParallelLoad(
@@ -232,65 +411,51 @@ func (self *BpAcc) G1ParallelLoad(
 	start uint64,
 	stop uint64,
 	wg *sync.WaitGroup) {
+	defer wg.Done()
+	check(self.g1SegmentLoad(fileName, varG, index, start, stop))
+}
 
-	f, err := os.Open(fileName)
-	check(err)
-
-	dataG1 := make([]byte, GetG1ByteSize())
+func (self *BpAcc) g1SegmentLoad(fileName string, varG []mcl.G1, index uint8, start uint64, stop uint64) error {
 
-	var resultG1 mcl.G1
+	f, r, err := self.openSegment(fileName, index, start, stop)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
 	for j := start; j < stop; j++ {
-
-		_, err = f.Read(dataG1)
-		check(err)
-		resultG1.Deserialize(dataG1)
-		varG[j] = resultG1
+		r.G1(&varG[j])
 	}
-	fmt.Println("Read ", fileName, BoundsPrint(start, stop))
-	defer f.Close()
-	defer wg.Done()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	self.logln("Read ", fileName, BoundsPrint(start, stop))
+	return nil
 }
 
 func (self *BpAcc) G1Load(files []string, varG []mcl.G1) {
+	check(self.g1Load(files, varG))
+}
 
-	var wg sync.WaitGroup
-	var step, start, stop uint64
-	var total, totalBytes int64
-	var i uint8
+func (self *BpAcc) g1Load(files []string, varG []mcl.G1) error {
+
+	var group errorGroup
+	group.SetLimit(self.workers())
 
 	if len(files) == 0 {
-		panic("Could not find the G1 files.")
+		return &FileError{self.folderPath, fmt.Errorf("%w: Could not find the G1 files.", ErrMissingFile)}
 	}
 
-	sort.Strings(files)
-
-	totalBytes = int64(0)
-	for i := range files {
-		totalBytes += fileSize(files[i])
+	parts, err := segmentParts(files, self.Q+1)
+	if err != nil {
+		return err
 	}
-
-	fmt.Println("Total bytes", totalBytes)
-	total = totalBytes / int64(GetG1ByteSize())
-	step = uint64(math.Ceil(float64(total) / float64(NFILES)))
-
-	num := self.Q + 1
-	start = uint64(0)
-	stop = step
-	stop = minUint64(stop, num)
-
-	i = uint8(0)
-	for start < num {
-		wg.Add(1)
-		fileName := files[i]
-		go self.G1ParallelLoad(fileName, varG, i, start, stop, &wg)
-		fmt.Println(fileName, i, start, stop)
-		start += step
-		stop += step
-		stop = minUint64(stop, num)
-		i++
+	for _, part := range parts {
+		part := part
+		group.Go(func() error { return self.g1SegmentLoad(part.fileName, varG, part.index, part.start, part.stop) })
+		self.logln(part.fileName, part.index, part.start, part.stop)
 	}
-	wg.Wait()
+	return group.Wait()
 }
 
 func (self *BpAcc) G2ParallelLoad(
@@ -300,119 +465,112 @@ func (self *BpAcc) G2ParallelLoad(
 	start uint64,
 	stop uint64,
 	wg *sync.WaitGroup) {
+	defer wg.Done()
+	check(self.g2SegmentLoad(fileName, varH, index, start, stop))
+}
 
-	f, err := os.Open(fileName)
-	check(err)
-
-	dataG2 := make([]byte, GetG2ByteSize())
+func (self *BpAcc) g2SegmentLoad(fileName string, varH []mcl.G2, index uint8, start uint64, stop uint64) error {
 
-	var resultG2 mcl.G2
+	f, r, err := self.openSegment(fileName, index, start, stop)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
 	for j := start; j < stop; j++ {
-
-		_, err = f.Read(dataG2)
-		check(err)
-		resultG2.Deserialize(dataG2)
-		varH[j] = resultG2
+		r.G2(&varH[j])
 	}
-	fmt.Println("Read ", fileName, BoundsPrint(start, stop))
-	defer f.Close()
-	defer wg.Done()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	self.logln("Read ", fileName, BoundsPrint(start, stop))
+	return nil
 }
 
 func (self *BpAcc) G2Load(files []string, varH []mcl.G2) {
-	var wg sync.WaitGroup
-	var step, start, stop uint64
-	var total, totalBytes int64
-	var i uint8
+	check(self.g2Load(files, varH))
+}
 
-	if len(files) == 0 {
-		panic("Could not find G2 files.")
-	}
+func (self *BpAcc) g2Load(files []string, varH []mcl.G2) error {
 
-	sort.Strings(files)
+	var group errorGroup
+	group.SetLimit(self.workers())
 
-	totalBytes = int64(0)
-	for i := range files {
-		totalBytes += fileSize(files[i])
+	if len(files) == 0 {
+		return &FileError{self.folderPath, fmt.Errorf("%w: Could not find G2 files.", ErrMissingFile)}
 	}
 
-	fmt.Println("Total bytes", totalBytes)
-	total = totalBytes / int64(GetG2ByteSize())
-	step = uint64(math.Ceil(float64(total) / float64(NFILES)))
-
-	num := self.Q + 1
-	start = uint64(0)
-	stop = step
-	stop = minUint64(stop, num)
-
-	i = uint8(0)
-	for start < num {
-		wg.Add(1)
-		fileName := files[i]
-		go self.G2ParallelLoad(fileName, varH, i, start, stop, &wg)
-		fmt.Println(fileName, i, start, stop)
-		start += step
-		stop += step
-		stop = minUint64(stop, num)
-		i++
+	parts, err := segmentParts(files, self.Q+1)
+	if err != nil {
+		return err
 	}
-	wg.Wait()
+	for _, part := range parts {
+		part := part
+		group.Go(func() error { return self.g2SegmentLoad(part.fileName, varH, part.index, part.start, part.stop) })
+		self.logln(part.fileName, part.index, part.start, part.stop)
+	}
+	return group.Wait()
 }
 
 func (self *BpAcc) IsParamsCorrect() bool {
 
 	if self.Q != uint64(1)<<self.ELL {
 		out_str := fmt.Sprintf("Q vs ELL: %d vs %d", self.Q, self.ELL)
-		fmt.Println(out_str)
+		self.logln(out_str)
+		return false
+	}
+
+	PK, VK, VKAlpha, PedVK, PedVKAlpha, err := self.allPowers()
+	if err != nil {
+		self.logln(err)
 		return false
 	}
 
 	if self.S.IsZero() == true {
 		out_str := fmt.Sprintf("Trapdoor S is zero.")
-		fmt.Println(out_str)
+		self.logln(out_str)
 		return false
 	}
 
 	if self.Alpha.IsZero() == true {
 		out_str := fmt.Sprintf("KEA trapdoor Alpha is zero.")
-		fmt.Println(out_str)
+		self.logln(out_str)
 		return false
 	}
 
-	if self.Q+1 != uint64(len(self.PK)) {
-		out_str := fmt.Sprintf("Q + 1 != len(PK): %d vs %d", self.Q, len(self.PK))
-		fmt.Println(out_str)
+	if self.Q+1 != uint64(len(PK)) {
+		out_str := fmt.Sprintf("Q + 1 != len(PK): %d vs %d", self.Q, len(PK))
+		self.logln(out_str)
 		return false
 	}
 
 	if len(self.PKAlpha) != 1 {
 		out_str := fmt.Sprintf("len(PK) != 1: %d vs 1", len(self.PKAlpha))
-		fmt.Println(out_str)
+		self.logln(out_str)
 		return false
 	}
 
-	if len(self.PK) != len(self.VK) {
-		out_str := fmt.Sprintf("len(PK) != len(VK): %d vs %d", len(self.PK), len(self.VK))
-		fmt.Println(out_str)
+	if len(PK) != len(VK) {
+		out_str := fmt.Sprintf("len(PK) != len(VK): %d vs %d", len(PK), len(VK))
+		self.logln(out_str)
 		return false
 	}
 
-	if len(self.VK) != len(self.VKAlpha) {
-		out_str := fmt.Sprintf("len(self.VK) != len(self.VKAlpha): %d vs %d", len(self.VK), len(self.VKAlpha))
-		fmt.Println(out_str)
+	if len(VK) != len(VKAlpha) {
+		out_str := fmt.Sprintf("len(self.VK) != len(self.VKAlpha): %d vs %d", len(VK), len(VKAlpha))
+		self.logln(out_str)
 		return false
 	}
 
-	if len(self.VK) != len(self.PedVK) {
-		out_str := fmt.Sprintf("len(self.VK) != len(self.PedVK): %d vs %d", len(self.VK), len(self.PedVK))
-		fmt.Println(out_str)
+	if len(VK) != len(PedVK) {
+		out_str := fmt.Sprintf("len(self.VK) != len(self.PedVK): %d vs %d", len(VK), len(PedVK))
+		self.logln(out_str)
 		return false
 	}
 
-	if len(self.PedVK) != len(self.PedVKAlpha) {
-		out_str := fmt.Sprintf("len(self.PedVK) != len(self.PedVKAlpha): %d vs %d", len(self.PedVK), len(self.PedVKAlpha))
-		fmt.Println(out_str)
+	if len(PedVK) != len(PedVKAlpha) {
+		out_str := fmt.Sprintf("len(self.PedVK) != len(self.PedVKAlpha): %d vs %d", len(PedVK), len(PedVKAlpha))
+		self.logln(out_str)
 		return false
 	}
 
@@ -422,10 +580,10 @@ func (self *BpAcc) IsParamsCorrect() bool {
 	if self.H.IsZero() {
 		return false
 	}
-	if !self.G.IsEqual(&self.PK[0]) {
+	if !self.G.IsEqual(&PK[0]) {
 		return false
 	}
-	if !self.H.IsEqual(&self.VK[0]) {
+	if !self.H.IsEqual(&VK[0]) {
 		return false
 	}
 
@@ -436,52 +594,52 @@ func (self *BpAcc) IsParamsCorrect() bool {
 	var g1Tmp mcl.G1
 	var g2Tmp mcl.G2
 
-	for i := 1; i < len(self.PK); i++ {
-		mcl.G1Mul(&g1Tmp, &self.PK[i-1], &self.S)
-		mcl.G2Mul(&g2Tmp, &self.VK[i-1], &self.S)
+	for i := 1; i < len(PK); i++ {
+		mcl.G1Mul(&g1Tmp, &PK[i-1], &self.S)
+		mcl.G2Mul(&g2Tmp, &VK[i-1], &self.S)
 
-		if !g1Tmp.IsEqual(&self.PK[i]) {
+		if !g1Tmp.IsEqual(&PK[i]) {
 			out_str := fmt.Sprintf("PK error at index %d", i)
-			fmt.Println(out_str)
+			self.logln(out_str)
 			return false
 		}
-		if !g2Tmp.IsEqual(&self.VK[i]) {
+		if !g2Tmp.IsEqual(&VK[i]) {
 			out_str := fmt.Sprintf("VK error at index %d", i)
-			fmt.Println(out_str)
+			self.logln(out_str)
 			return false
 		}
 
-		mcl.G2Mul(&g2Tmp, &self.VK[i], &self.Alpha)
-		if !g2Tmp.IsEqual(&self.VKAlpha[i]) {
+		mcl.G2Mul(&g2Tmp, &VK[i], &self.Alpha)
+		if !g2Tmp.IsEqual(&VKAlpha[i]) {
 			out_str := fmt.Sprintf("KEA VKAlpha error at index %d", i)
-			fmt.Println(out_str)
+			self.logln(out_str)
 			return false
 		}
 
-		mcl.G2Mul(&g2Tmp, &self.VKAlpha[i-1], &self.S)
-		if !g2Tmp.IsEqual(&self.VKAlpha[i]) {
+		mcl.G2Mul(&g2Tmp, &VKAlpha[i-1], &self.S)
+		if !g2Tmp.IsEqual(&VKAlpha[i]) {
 			out_str := fmt.Sprintf("VKAlpha error at index %d", i)
-			fmt.Println(out_str)
+			self.logln(out_str)
 			return false
 		}
 
-		mcl.G2Mul(&g2Tmp, &self.PedVK[i-1], &self.S)
-		if !g2Tmp.IsEqual(&self.PedVK[i]) {
+		mcl.G2Mul(&g2Tmp, &PedVK[i-1], &self.S)
+		if !g2Tmp.IsEqual(&PedVK[i]) {
 			out_str := fmt.Sprintf("PedVK error at index %d", i)
-			fmt.Println(out_str)
+			self.logln(out_str)
 			return false
 		}
 
-		mcl.G2Mul(&g2Tmp, &self.PedVK[i], &self.Alpha)
-		if !g2Tmp.IsEqual(&self.PedVKAlpha[i]) {
+		mcl.G2Mul(&g2Tmp, &PedVK[i], &self.Alpha)
+		if !g2Tmp.IsEqual(&PedVKAlpha[i]) {
 			out_str := fmt.Sprintf("PedVKAlpha error at index %d", i)
-			fmt.Println(out_str)
+			self.logln(out_str)
 			return false
 		}
 
-		if self.PK[i].IsZero() == true || self.VK[i].IsZero() == true || self.VKAlpha[i].IsZero() == true || self.PedVK[i].IsZero() == true || self.PedVKAlpha[i].IsZero() == true {
+		if PK[i].IsZero() == true || VK[i].IsZero() == true || VKAlpha[i].IsZero() == true || PedVK[i].IsZero() == true || PedVKAlpha[i].IsZero() == true {
 			out_str := fmt.Sprintf("One of the PP is zero.")
-			fmt.Println(out_str)
+			self.logln(out_str)
 			return false
 		}
 	}
@@ -2,14 +2,18 @@ package bpacc
 
 import (
 	"fmt"
-	"log"
 	"math/bits"
-	"os"
 
 	"github.com/accumulators-agg/go-poly/fft"
 	"github.com/alinush/go-mcl"
 )
 
+// fft.PolyTree pads its input with append, which overwrites whatever follows the slice in its backing array.
+// Capping the capacity makes append copy instead.
+func polyTree(elements []mcl.Fr) []mcl.Fr {
+	return fft.PolyTree(elements[:len(elements):len(elements)])
+}
+
 // Converts a string to Fr element
 func SeedToFr(seed string) mcl.Fr {
 	var s mcl.Fr
@@ -24,37 +28,24 @@ func SeedToFr(seed string) mcl.Fr {
 	return s
 }
 
-// Some default generators. Not relevant for this project.
-// But a pre-agreed generator is used so that prover and verifier can get to business without making sure that they have the same parameters.
+// Default generators, hashed to the curve from GENERATORS_LABEL (see acc-generators.go).
+// A pre-agreed generator is used so that prover and verifier can get to business without making sure that they have the same parameters.
 func initG1G2() (mcl.G1, mcl.G2) {
-	var GenG1 mcl.G1
-	var GenG2 mcl.G2
-	GenG1.X.SetString("3685416753713387016781088315183077757961620795782546409894578378688607592378376318836054947676345821548104185464507", 10)
-	GenG1.Y.SetString("1339506544944476473020471379941921221584933875938349620426543736416511423956333506472724655353366534992391756441569", 10)
-	GenG1.Z.SetInt64(1)
-
-	GenG2.X.D[0].SetString("352701069587466618187139116011060144890029952792775240219908644239793785735715026873347600343865175952761926303160", 10)
-	GenG2.X.D[1].SetString("3059144344244213709971259814753781636986470325476647558659373206291635324768958432433509563104347017837885763365758", 10)
-	GenG2.Y.D[0].SetString("1985150602287291935568054521177171638300868978215655730859378665066344726373823718423869104263333984641494340347905", 10)
-	GenG2.Y.D[1].SetString("927553665492332455747201965776037880757740193453592970025027978793976877002675564980949289727957565575433344219582", 10)
-	GenG2.Z.D[0].SetInt64(1)
-	GenG2.Z.D[1].Clear()
-
-	GenG1.Random()
-	GenG2.Random()
-	return GenG1, GenG2
+	return HashToG1(GENERATORS_LABEL, "G"), HashToG2(GENERATORS_LABEL, "H")
 }
 
 func GetFrByteSize() int {
 	return 32
 }
 
-func GetG1ByteSize() int {
-	return 48
+// Size of a G1 point in Options.Encoding.
+func (self *BpAcc) GetG1ByteSize() int {
+	return self.opts.Encoding.G1Size()
 }
 
-func GetG2ByteSize() int {
-	return 96
+// Size of a G2 point in Options.Encoding.
+func (self *BpAcc) GetG2ByteSize() int {
+	return self.opts.Encoding.G2Size()
 }
 
 func GetGTByteSize() int {
@@ -71,19 +62,18 @@ func check(e error) {
 	}
 }
 
-func minUint64(a uint64, b uint64) uint64 {
+func minInt(a int, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
 
-func fileSize(path string) int64 {
-	fi, err := os.Stat(path)
-	if err != nil {
-		log.Fatal(err)
+func minUint64(a uint64, b uint64) uint64 {
+	if a < b {
+		return a
 	}
-	return fi.Size()
+	return b
 }
 
 // Computes the a^x, where a is mcl.Fr and x is int64
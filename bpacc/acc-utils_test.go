@@ -114,3 +114,44 @@ func BenchmarkComputeYsVec(b *testing.B) {
 		}
 	}
 }
+
+func TestPolyTreeKeepsInput(t *testing.T) {
+	elements := PopulateRandom(8)
+	expected := append([]mcl.Fr{}, elements...)
+
+	// The bug: fft.PolyTree pads 5 elements to 8 with zeros written over elements[5:8]
+	aliased := append([]mcl.Fr{}, elements...)
+	fft.PolyTree(aliased[:5])
+	if !aliased[5].IsZero() || !aliased[7].IsZero() {
+		t.Errorf("fft.PolyTree no longer pads in place, polyTree may be dropped")
+	}
+
+	polyTree(elements[:5])
+	for i := range elements {
+		if !elements[i].IsEqual(&expected[i]) {
+			t.Errorf("Element %d was overwritten", i)
+		}
+	}
+}
+
+// MemProve concatenates X and I; X with spare capacity must not get I written past its end
+func TestMemProveKeepsInput(t *testing.T) {
+	var acc BpAcc
+	acc.KeyGenMem(8, 5, "xyz")
+	elements := PopulateRandom(12)
+	expected := append([]mcl.Fr{}, elements...)
+
+	X, I := elements[:4], elements[8:10]
+	digest, _ := acc.Commit(append(append([]mcl.Fr{}, X...), I...))
+	proofs := acc.MemProve(X, I)
+	for i := range elements {
+		if !elements[i].IsEqual(&expected[i]) {
+			t.Errorf("Element %d was overwritten", i)
+		}
+	}
+	for i := range I {
+		if !acc.MemVerifySingle(digest, I[i], proofs[i]) {
+			t.Errorf("Proof %d did not verify", i)
+		}
+	}
+}
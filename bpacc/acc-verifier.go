@@ -0,0 +1,260 @@
+package bpacc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/alinush/go-mcl"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Verifier bundle: everything the verifiers need for batches of up to MaxBatch elements, in a single file.
+// That is G, H, PKAlpha[0], PedH (= PedVK[0]), A[0], B[0], VK[0..MaxBatch] and PK[Q-MaxBatch+1..Q] for ZKDegCheckVerifier.
+// The header records the VK prefix as the range [0, MaxBatch+1), and the file ends with the blake2b-256 hash of the rest.
+// Gneg, Hneg, IdGT and InvIdGT are derived when loading.
+
+const VERIFIER_COMPONENT = "verifier"
+
+// Writes the verifier bundle for batches of up to maxBatch elements to path.
+// Works with both the in-memory and the mapped storage.
+func (self *BpAcc) ExportVerifier(maxBatch uint64, path string) {
+	check(self.TryExportVerifier(maxBatch, path))
+}
+
+// Same as ExportVerifier, but I/O failures are returned instead of panicking.
+func (self *BpAcc) TryExportVerifier(maxBatch uint64, path string) error {
+
+	if maxBatch == 0 || maxBatch > self.Q {
+		return &FileError{path, fmt.Errorf("%w: batches of %d elements, wants 1 to %d", ErrFormat, maxBatch, self.Q)}
+	}
+	self.logln("Saving data to:", path)
+
+	var buf bytes.Buffer
+	header := self.newFileHeader(VERIFIER_COMPONENT, 0, 0, maxBatch+1)
+	if err := WriteHeader(&buf, header); err != nil {
+		return &FileError{path, err}
+	}
+	w := pointWriter{w: &buf, path: path, encoding: PointEncoding(header.Encoding)}
+
+	w.G1(&self.G)
+	w.G2(&self.H)
+	w.G1(&self.PKAlpha[0])
+	w.G2(&self.PedH)
+	w.G1(&self.A[0])
+	w.G2(&self.B[0])
+
+	vk := self.vkRange(0, maxBatch+1)
+	for i := range vk {
+		w.G2(&vk[i])
+	}
+	pk := self.pkRange(self.Q-maxBatch+1, self.Q+1)
+	for i := range pk {
+		w.G1(&pk[i])
+	}
+	if err := w.Err(); err != nil {
+		return err
+	}
+
+	hash := blake2b.Sum256(buf.Bytes())
+	buf.Write(hash[:])
+
+	os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return &FileError{path, err}
+	}
+	return nil
+}
+
+// Verifies proofs with the verifier bundle only.
+// Batches larger than MaxBatch are rejected. The proofs are expected in the encoding of the bundle,
+// that is the Options.Encoding of the accumulator that exported it.
+type BpVerifier struct {
+	MaxBatch uint64
+	acc      BpAcc // Holds VK[0..MaxBatch] and the tail of PK only
+}
+
+func (self *BpVerifier) Load(path string) {
+	check(self.TryLoad(path))
+}
+
+// Same as Load, but I/O failures are returned instead of panicking.
+func (self *BpVerifier) TryLoad(path string) error {
+
+	f, err := openFile(path)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return &FileError{path, err}
+	}
+	if len(data) < HEADER_SIZE+blake2b.Size256 {
+		return &FileError{path, ErrShortRead}
+	}
+	body := data[:len(data)-blake2b.Size256]
+	hash := blake2b.Sum256(body)
+	if !bytes.Equal(hash[:], data[len(body):]) {
+		return &FileError{path, fmt.Errorf("%w: does not match its hash", ErrFormat)}
+	}
+
+	in := bytes.NewReader(body)
+	header, err := ReadHeader(in)
+	if err == nil {
+		err = header.Expect(VERIFIER_COMPONENT, 0)
+	}
+	if err == nil && (header.ELL >= 64 || header.Start != 0 || header.Stop < 2 || header.Stop-1 > uint64(1)<<header.ELL) {
+		err = fmt.Errorf("%w: holds [%d, %d) with ELL %d", ErrFormat, header.Start, header.Stop, header.ELL)
+	}
+	if err != nil {
+		return &FileError{path, err}
+	}
+
+	maxBatch := header.Stop - 1
+	var acc BpAcc
+	acc.initShared(header.ELL, "", filepath.Dir(path))
+	acc.opts.Encoding = PointEncoding(header.Encoding)
+	acc.VK = make([]mcl.G2, maxBatch+1)
+	acc.PK = make([]mcl.G1, maxBatch)
+	acc.pkStart = acc.Q - maxBatch + 1
+
+	r := pointReader{r: in, path: path, encoding: acc.reading(PointEncoding(header.Encoding))}
+	r.G1(&acc.G)
+	r.G2(&acc.H)
+	r.G1(&acc.PKAlpha[0])
+	r.G2(&acc.PedH)
+	r.G1(&acc.A[0])
+	r.G2(&acc.B[0])
+	for i := range acc.VK {
+		r.G2(&acc.VK[i])
+	}
+	for i := range acc.PK {
+		r.G1(&acc.PK[i])
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if in.Len() != 0 {
+		return &FileError{path, fmt.Errorf("%w: %d bytes too many", ErrFormat, in.Len())}
+	}
+	acc.A, acc.B = acc.A[:1], acc.B[:1]
+
+	mcl.G1Neg(&acc.Gneg, &acc.G)
+	mcl.G2Neg(&acc.Hneg, &acc.H)
+	mcl.Pairing(&acc.IdGT, &acc.G, &acc.H)
+	mcl.GTInv(&acc.InvIdGT, &acc.IdGT)
+
+	self.MaxBatch = maxBatch
+	self.acc = acc
+	return nil
+}
+
+func (self *BpVerifier) ELL() uint64 {
+	return self.acc.ELL
+}
+
+func (self *BpVerifier) Q() uint64 {
+	return self.acc.Q
+}
+
+// G and H, e.g. for NiPoEVerifyG2.
+func (self *BpVerifier) Generators() (mcl.G1, mcl.G2) {
+	return self.acc.G, self.acc.H
+}
+
+func (self *BpVerifier) MemVerifySingle(digest mcl.G1, I mcl.Fr, proof mcl.G1) bool {
+	return self.acc.MemVerifySingle(digest, I, proof)
+}
+
+func (self *BpVerifier) NonMemVerifySingle(digest mcl.G1, y mcl.Fr, pi *NonMemProof) bool {
+	return self.acc.NonMemVerifySingle(digest, y, pi)
+}
+
+func (self *BpVerifier) MemVerifySingleBytes(digest mcl.G1, domain string, data []byte, proof mcl.G1) bool {
+	return self.acc.MemVerifySingleBytes(digest, domain, data, proof)
+}
+
+func (self *BpVerifier) NonMemVerifySingleBytes(digest mcl.G1, domain string, data []byte, pi *NonMemProof) bool {
+	return self.acc.NonMemVerifySingleBytes(digest, domain, data, pi)
+}
+
+func (self *BpVerifier) AggMemVerify(digest mcl.G1, I []mcl.Fr, proof mcl.G1) bool {
+	if uint64(len(I)) > self.MaxBatch {
+		return false
+	}
+	return self.acc.AggMemVerify(digest, I, proof)
+}
+
+func (self *BpVerifier) AggMemVerifyPoE(digest mcl.G1, I []mcl.Fr, proof mcl.G1, Q1 mcl.G1, Q2 mcl.G2) bool {
+	if uint64(len(I)) > self.MaxBatch {
+		return false
+	}
+	return self.acc.AggMemVerifyPoE(digest, I, proof, Q1, Q2)
+}
+
+func (self *BpVerifier) AggNonMemVerify(digest mcl.G1, alphaOfS mcl.G2, betaOfS mcl.G1, I []mcl.Fr) bool {
+	if uint64(len(I)) > self.MaxBatch {
+		return false
+	}
+	return self.acc.AggNonMemVerify(digest, alphaOfS, betaOfS, I)
+}
+
+func (self *BpVerifier) AggNonMemVerifyPoE(digest mcl.G1, alphaOfS mcl.G2, betaOfS mcl.G1,
+	Q1 mcl.G1, Q2 mcl.G1, w mcl.G2, I []mcl.Fr) bool {
+	if uint64(len(I)) > self.MaxBatch {
+		return false
+	}
+	return self.acc.AggNonMemVerifyPoE(digest, alphaOfS, betaOfS, Q1, Q2, w, I)
+}
+
+func (self *BpVerifier) VerifyBatchMem(digest mcl.G1, I []mcl.Fr, witness mcl.G1) bool {
+	if uint64(len(I)) > self.MaxBatch {
+		return false
+	}
+	return self.acc.VerifyBatchMem(digest, I, witness)
+}
+
+func (self *BpVerifier) VerifyBatchNonMem(digest mcl.G1, I []mcl.Fr, A mcl.G2, B mcl.G1) bool {
+	if uint64(len(I)) > self.MaxBatch {
+		return false
+	}
+	return self.acc.VerifyBatchNonMem(digest, I, A, B)
+}
+
+func (self *BpVerifier) VerifyBatchUpdate(digest mcl.G1, update *BatchUpdate) bool {
+	return self.acc.VerifyBatchUpdate(digest, update)
+}
+
+// v has the degree of the batch, at most MaxBatch.
+func (self *BpVerifier) NiPoEVerifyG1(Q1 mcl.G1, Q2 mcl.G2, w mcl.G1, u mcl.G1, v []mcl.Fr) bool {
+	if len(v) == 0 || uint64(len(v)-1) > self.MaxBatch {
+		return false
+	}
+	return self.acc.NiPoEVerifyG1(Q1, Q2, w, u, v)
+}
+
+// v has the degree of the batch, at most MaxBatch.
+func (self *BpVerifier) NiPoEVerifyG2(Q1 mcl.G1, Q2 mcl.G1, w mcl.G2, u mcl.G2, v []mcl.Fr) bool {
+	if len(v) == 0 || uint64(len(v)-1) > self.MaxBatch {
+		return false
+	}
+	return self.acc.NiPoEVerifyG2(Q1, Q2, w, u, v)
+}
+
+func (self *BpVerifier) ZKDegCheckVerifier(C_I mcl.G2, proof ZKDegCheckProof, transcript [32]byte) bool {
+	if proof.D == 0 || proof.D > self.MaxBatch {
+		return false
+	}
+	return self.acc.ZKDegCheckVerifier(C_I, proof, transcript)
+}
+
+func (self *BpVerifier) ZKMemVerifier(proof zkMemProof, A_X mcl.G1, C_I mcl.G2, transcript [32]byte) bool {
+	return self.acc.ZKMemVerifier(proof, A_X, C_I, transcript)
+}
+
+func (self *BpVerifier) ZKNonMemVerifier(proof zkNonMemProof, digest mcl.G1, C_I mcl.G2, transcript [32]byte) bool {
+	return self.acc.ZKNonMemVerifier(proof, digest, C_I, transcript)
+}
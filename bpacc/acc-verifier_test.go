@@ -0,0 +1,117 @@
+package bpacc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/accumulators-agg/go-poly/fft"
+	"github.com/alinush/go-mcl"
+)
+
+func TestVerifierBundle(t *testing.T) {
+
+	L, maxBatch := uint64(5), uint64(8)
+	folder := t.TempDir()
+	path := filepath.Join(t.TempDir(), "verifier.data")
+
+	var acc BpAcc
+	acc.KeyGen(8, L, "xyz", folder)
+	acc.ExportVerifier(maxBatch, path)
+
+	var verifier BpVerifier
+	verifier.Load(path)
+	if verifier.MaxBatch != maxBatch || verifier.ELL() != L || verifier.Q() != acc.Q {
+		t.Fatalf("Bundle reports %d, %d, %d", verifier.MaxBatch, verifier.ELL(), verifier.Q())
+	}
+
+	n := uint64(2 * maxBatch)
+	elements := PopulateRandom(n + 1)
+	X, I, y := elements[:n/2], elements[n/2:n], elements[n]
+	digestXI, _ := acc.Commit(elements[:n])
+	digestX, _ := acc.Commit(X)
+
+	memProofs := acc.MemProve(X, I)
+	nonMemProofs := acc.NonMemProve(X, []mcl.Fr{y})
+	if !verifier.MemVerifySingle(digestXI, I[0], memProofs[0]) {
+		t.Errorf("Membership proof did not verify")
+	}
+	if !verifier.NonMemVerifySingle(digestX, y, &nonMemProofs[0]) {
+		t.Errorf("Non-membership proof did not verify")
+	}
+	proof, _ := acc.AggMemProve(I, memProofs)
+	if !verifier.AggMemVerify(digestXI, I, proof) {
+		t.Errorf("Aggregated membership proof did not verify")
+	}
+	if verifier.AggMemVerify(digestXI, elements[:n], proof) {
+		t.Errorf("Batch larger than MaxBatch verified")
+	}
+
+	proof, Q1, Q2, _ := acc.AggMemProvePoE(digestXI, I, memProofs)
+	if !verifier.AggMemVerifyPoE(digestXI, I, proof, Q1, Q2) {
+		t.Errorf("Aggregated membership proof with PoE did not verify")
+	}
+	allProofs := acc.MemProve(nil, elements[:n])
+	proof, Q1, Q2, I_s := acc.AggMemProvePoE(digestXI, elements[:n], allProofs)
+	if verifier.AggMemVerifyPoE(digestXI, elements[:n], proof, Q1, Q2) {
+		t.Errorf("PoE batch larger than MaxBatch verified")
+	}
+	if verifier.NiPoEVerifyG1(Q1, Q2, digestXI, proof, I_s) {
+		t.Errorf("PoE of a polynomial above MaxBatch verified")
+	}
+
+	transcript := [32]byte{}
+	var random mcl.Fr
+	random.Random()
+	accPoly := fft.PolyTree(I)
+	C_I := PedG2{acc.PedersenG2(accPoly, acc.VK, random, acc.PedVK[0]), random}
+
+	degProof := acc.ZKDegCheckProver(C_I, accPoly, transcript)
+	if !verifier.ZKDegCheckVerifier(C_I.Com, degProof, transcript) {
+		t.Errorf("Degree check did not verify")
+	}
+	witness := acc.ProveBatchMem(elements[:n], I)
+	if !verifier.VerifyBatchMem(digestXI, I, witness) {
+		t.Errorf("Batch membership did not verify")
+	}
+	memProof := acc.ZKMemProver(C_I, witness, transcript)
+	if !verifier.ZKMemVerifier(memProof, digestXI, C_I.Com, transcript) {
+		t.Errorf("ZK membership did not verify")
+	}
+	A, B := acc.ProveBatchNonMem(X, I)
+	if !verifier.VerifyBatchNonMem(digestX, I, A, B) {
+		t.Errorf("Batch non-membership did not verify")
+	}
+	nonMemProof := acc.ZKNonMemProver(digestX, C_I, A, B, transcript)
+	if !verifier.ZKNonMemVerifier(nonMemProof, digestX, C_I.Com, transcript) {
+		t.Errorf("ZK non-membership did not verify")
+	}
+
+	accPoly = fft.PolyTree(elements[:n])
+	C_I = PedG2{acc.PedersenG2(accPoly, acc.VK, random, acc.PedVK[0]), random}
+	degProof = acc.ZKDegCheckProver(C_I, accPoly, transcript)
+	if verifier.ZKDegCheckVerifier(C_I.Com, degProof, transcript) {
+		t.Errorf("Degree check above MaxBatch verified")
+	}
+
+	// A much smaller file than the folder
+	info, err := os.Stat(path)
+	check(err)
+	wants := int64(HEADER_SIZE) + int64(3*acc.GetG1ByteSize()+3*acc.GetG2ByteSize()) +
+		int64(maxBatch+1)*int64(acc.GetG2ByteSize()) + int64(maxBatch)*int64(acc.GetG1ByteSize()) + 32
+	if info.Size() != wants {
+		t.Errorf("Bundle has %d bytes, wants %d", info.Size(), wants)
+	}
+
+	data, err := os.ReadFile(path)
+	check(err)
+	data[HEADER_SIZE] ^= 1
+	check(os.WriteFile(path, data, 0644))
+	if err := verifier.TryLoad(path); !errors.Is(err, ErrFormat) {
+		t.Errorf("Corrupted bundle reported as: %v", err)
+	}
+	if err := acc.TryExportVerifier(acc.Q+1, path); !errors.Is(err, ErrFormat) {
+		t.Errorf("Oversized bundle reported as: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package bpacc
+
+import (
+	"fmt"
+
+	"github.com/accumulators-agg/go-poly/fft"
+	"github.com/alinush/go-mcl"
+)
+
+// Updates of the witnesses after the digest changed, from public values only (Nguyen).
+// Let V be the digest before the change and V' after it. For the membership witness w = V^{1/(s-y)} of y:
+// Adding y': V' = V^{s-y'}, and w' = V'^{1/(s-y)} = V w^{y-y'}
+// Deleting y': V' = V^{1/(s-y')}, and w' = V'^{1/(s-y)} = (V' / w)^{1/(y'-y)}
+// For the non-membership witness (alpha, g^{b(s)}) of y, where alpha f(x) + b(x)(x-y) = 1, thus alpha = 1/f(y):
+// Adding y': alpha' = alpha/(y-y'), and g^{b'(s)} = g^{b(s)} V^{-alpha'}
+// Deleting y': alpha' = alpha(y-y'), and g^{b'(s)} = g^{b(s)} V'^{alpha}
+// Updates of a batch are applied one element at a time, thus they need the digest after every element,
+// see DynAcc.AddWithUpdates and DynAcc.DeleteWithUpdates.
+
+// One element added to or deleted from the accumulator, with the digests before and after.
+type DigestUpdate struct {
+	Element mcl.Fr
+	Deleted bool
+	Old     mcl.G1
+	New     mcl.G1
+}
+
+// Membership witness of y after adding the element to the accumulator with digest old (before the addition).
+// Computes one exponentiation.
+func MemWitnessAdd(w mcl.G1, y mcl.Fr, old mcl.G1, added mcl.Fr) mcl.G1 {
+	var e mcl.Fr
+	var updated mcl.G1
+	mcl.FrSub(&e, &y, &added)
+	mcl.G1Mul(&updated, &w, &e)
+	mcl.G1Add(&updated, &updated, &old)
+	return updated
+}
+
+// Membership witness of y after deleting the element, where digest is the one after the deletion.
+// Panics if y itself is deleted.
+// Computes one inversion and one exponentiation.
+func MemWitnessDelete(w mcl.G1, y mcl.Fr, digest mcl.G1, deleted mcl.Fr) mcl.G1 {
+	var e mcl.Fr
+	var updated mcl.G1
+	mcl.FrSub(&e, &deleted, &y)
+	if e.IsZero() {
+		panic("The element of the witness is the one deleted.")
+	}
+	mcl.FrInv(&e, &e)
+	mcl.G1Sub(&updated, &digest, &w)
+	mcl.G1Mul(&updated, &updated, &e)
+	return updated
+}
+
+// Applies the updates in order to the membership witness of y.
+func MemWitnessUpdate(w mcl.G1, y mcl.Fr, updates []DigestUpdate) mcl.G1 {
+	for i := range updates {
+		if updates[i].Deleted {
+			w = MemWitnessDelete(w, y, updates[i].New, updates[i].Element)
+		} else {
+			w = MemWitnessAdd(w, y, updates[i].Old, updates[i].Element)
+		}
+	}
+	return w
+}
+
+// Non-membership witness of y after adding the element to the accumulator with digest old (before the addition).
+// Panics if y itself is added.
+// Computes one inversion and one exponentiation.
+func NonMemWitnessAdd(pi NonMemProof, y mcl.Fr, old mcl.G1, added mcl.Fr) NonMemProof {
+	var e mcl.Fr
+	var updated NonMemProof
+	var temp mcl.G1
+	mcl.FrSub(&e, &y, &added)
+	if e.IsZero() {
+		panic("The element of the witness is the one added.")
+	}
+	mcl.FrDiv(&updated.Alpha, &pi.Alpha, &e)
+	mcl.G1Mul(&temp, &old, &updated.Alpha)
+	mcl.G1Sub(&updated.Beta, &pi.Beta, &temp)
+	return updated
+}
+
+// Non-membership witness of y after deleting the element, where digest is the one after the deletion.
+// Computes one exponentiation.
+func NonMemWitnessDelete(pi NonMemProof, y mcl.Fr, digest mcl.G1, deleted mcl.Fr) NonMemProof {
+	var e mcl.Fr
+	var updated NonMemProof
+	var temp mcl.G1
+	mcl.FrSub(&e, &y, &deleted)
+	mcl.FrMul(&updated.Alpha, &pi.Alpha, &e)
+	mcl.G1Mul(&temp, &digest, &pi.Alpha)
+	mcl.G1Add(&updated.Beta, &pi.Beta, &temp)
+	return updated
+}
+
+// Applies the updates in order to the non-membership witness of y.
+func NonMemWitnessUpdate(pi NonMemProof, y mcl.Fr, updates []DigestUpdate) NonMemProof {
+	for i := range updates {
+		if updates[i].Deleted {
+			pi = NonMemWitnessDelete(pi, y, updates[i].New, updates[i].Element)
+		} else {
+			pi = NonMemWitnessAdd(pi, y, updates[i].Old, updates[i].Element)
+		}
+	}
+	return pi
+}
+
+// Batch updates: a single message for a whole batch Y of additions or deletions, with p(x) the product of (x - y') over Y.
+// Write p(x) = p(y) + (x-y)q(x), and let Omega_i = V^{s^i} for i = 0 .. |Y|, where V is the digest before an addition
+// or after a deletion. Then, with f the polynomial of the larger set,
+// Adding Y: w' = w^{p(y)} g^{f(s)q(s)} = w^{p(y)} prod Omega_i^{q_i}
+// Deleting Y: w = w'^{p(y)} prod Omega_i^{q_i}, thus w' = (w / prod Omega_i^{q_i})^{1/p(y)}
+// Every holder computes p(x) and q(x) with field operations only, and then one multi-exp of size |Y| + 1.
+
+// Published by the manager, see DynAcc.AddWithBatchUpdate and DynAcc.DeleteWithBatchUpdate.
+type BatchUpdate struct {
+	Elements []mcl.Fr
+	Deleted  bool
+	Omega    []mcl.G1 // V^{s^i} for i = 0 .. |Elements|
+}
+
+// The digest after the update.
+func (self *BatchUpdate) NewDigest() mcl.G1 {
+	if self.Deleted {
+		return self.Omega[0]
+	}
+	var digest mcl.G1
+	mcl.G1MulVec(&digest, self.Omega, polyTree(self.Elements))
+	return digest
+}
+
+// Checks the update against digest, the digest before the update that the holder already trusts
+// (the one its witness verifies against), and that the Omega_i are consecutive powers of s over Omega_0.
+// Adding: Omega_0 = digest. Deleting: prod Omega_i^{p_i} = V^{p(s)} = digest.
+// The powers are checked with a random linear combination:
+// e(sum r_i Omega_i, h) = e(sum r_i Omega_{i-1}, h^s) for i = 1 .. |Elements|.
+// Holders only need this if they do not trust the channel the message came from.
+func (self *BpAcc) VerifyBatchUpdate(digest mcl.G1, update *BatchUpdate) bool {
+	k := len(update.Elements)
+	if k == 0 || len(update.Omega) != k+1 {
+		return false
+	}
+	if update.Deleted {
+		var old mcl.G1
+		mcl.G1MulVec(&old, update.Omega, polyTree(update.Elements))
+		if !old.IsEqual(&digest) {
+			return false
+		}
+	} else if !update.Omega[0].IsEqual(&digest) {
+		return false
+	}
+
+	r := make([]mcl.Fr, k)
+	for i := range r {
+		self.randomFr(&r[i])
+	}
+	var P, Q mcl.G1
+	mcl.G1MulVec(&P, update.Omega[1:], r)
+	mcl.G1MulVec(&Q, update.Omega[:k], r)
+	return MultiPairing2(P, self.H, Q, self.vkAt(1))
+}
+
+// Membership witness of y after the batch update, in one multi-exp.
+// Panics if y is one of the elements of the update.
+func MemWitnessBatchUpdate(w mcl.G1, y mcl.Fr, update *BatchUpdate) mcl.G1 {
+	k := len(update.Elements)
+	if k == 0 {
+		return w
+	}
+	if len(update.Omega) != k+1 {
+		panic(fmt.Sprintf("Batch update of %d elements holds %d powers, wants %d", k, len(update.Omega), k+1))
+	}
+
+	x := make([]mcl.Fr, 2)
+	mcl.FrNeg(&x[0], &y)
+	x[1].SetInt64(1)
+	p := polyTree(update.Elements)
+	q, remainder := fft.PolyDiv(p, x) // remainder = p(y)
+	if remainder[0].IsZero() {
+		panic("The element of the witness is in the batch update.")
+	}
+
+	bases := append([]mcl.G1{w}, update.Omega[:k]...)
+	exps := make([]mcl.Fr, k+1)
+	if update.Deleted {
+		var c mcl.Fr
+		mcl.FrInv(&c, &remainder[0])
+		exps[0] = c
+		for i := range q {
+			mcl.FrMul(&exps[i+1], &q[i], &c)
+			mcl.FrNeg(&exps[i+1], &exps[i+1])
+		}
+	} else {
+		exps[0] = remainder[0]
+		copy(exps[1:], q)
+	}
+
+	var updated mcl.G1
+	mcl.G1MulVec(&updated, bases, exps)
+	return updated
+}
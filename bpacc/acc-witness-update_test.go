@@ -0,0 +1,152 @@
+package bpacc
+
+import (
+	"testing"
+
+	"github.com/alinush/go-mcl"
+)
+
+func TestMemWitnessUpdate(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 6, "xyz")
+	elements := PopulateRandom(30)
+
+	set, y := elements[:19], elements[19]
+	manager := NewDynAcc(&acc, elements[:20])
+	w := acc.MemProve(set, []mcl.Fr{y})[0]
+
+	// A single addition, with the digests of Add
+	old := manager.Digest
+	manager.Add(elements[20:21])
+	w = MemWitnessAdd(w, y, old, elements[20])
+	if !acc.MemVerifySingle(manager.Digest, y, w) {
+		t.Errorf("Witness did not verify after one addition")
+	}
+
+	updates := manager.AddWithUpdates(elements[21:30])
+	w = MemWitnessUpdate(w, y, updates)
+	if !acc.MemVerifySingle(manager.Digest, y, w) {
+		t.Errorf("Witness did not verify after the additions")
+	}
+
+	updates = manager.DeleteWithUpdates(append(append([]mcl.Fr{}, elements[3:8]...), elements[25:27]...))
+	w = MemWitnessUpdate(w, y, updates)
+	if !acc.MemVerifySingle(manager.Digest, y, w) {
+		t.Errorf("Witness did not verify after the deletions")
+	}
+
+	// Same as a fresh witness
+	remaining := append(append(append([]mcl.Fr{}, elements[:3]...), elements[8:19]...), elements[20:25]...)
+	remaining = append(remaining, elements[27:30]...)
+	fresh := acc.MemProve(remaining, []mcl.Fr{y})[0]
+	if !fresh.IsEqual(&w) {
+		t.Errorf("Updated witness differs from MemProve")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Deleting the element of the witness did not panic")
+		}
+	}()
+	MemWitnessDelete(w, y, manager.Digest, y)
+}
+
+func TestNonMemWitnessUpdate(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 6, "xyz")
+	elements := PopulateRandom(30)
+
+	set, y := elements[:20], elements[29]
+	manager := NewDynAcc(&acc, set)
+	pi := acc.NonMemProve(set, []mcl.Fr{y})[0]
+
+	old := manager.Digest
+	manager.Add(elements[20:21])
+	pi = NonMemWitnessAdd(pi, y, old, elements[20])
+	if !acc.NonMemVerifySingle(manager.Digest, y, &pi) {
+		t.Errorf("Witness did not verify after one addition")
+	}
+
+	updates := manager.AddWithUpdates(elements[21:29])
+	updates = append(updates, manager.DeleteWithUpdates(append(append([]mcl.Fr{}, elements[3:8]...), elements[25:27]...))...)
+	pi = NonMemWitnessUpdate(pi, y, updates)
+	if !acc.NonMemVerifySingle(manager.Digest, y, &pi) {
+		t.Errorf("Witness did not verify after the additions and deletions")
+	}
+
+	remaining := append(append(append([]mcl.Fr{}, elements[:3]...), elements[8:25]...), elements[27:29]...)
+	fresh := acc.NonMemProve(remaining, []mcl.Fr{y})[0]
+	if !fresh.Alpha.IsEqual(&pi.Alpha) || !fresh.Beta.IsEqual(&pi.Beta) {
+		t.Errorf("Updated witness differs from NonMemProve")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Adding the element of the witness did not panic")
+		}
+	}()
+	NonMemWitnessAdd(pi, y, manager.Digest, y)
+}
+
+func TestMemWitnessBatchUpdate(t *testing.T) {
+
+	var acc BpAcc
+	acc.KeyGenMem(8, 6, "xyz")
+	elements := PopulateRandom(40)
+
+	set, y := elements[:19], elements[19]
+	manager := NewDynAcc(&acc, elements[:20])
+	w := acc.MemProve(set, []mcl.Fr{y})[0]
+	single := w
+
+	added := elements[20:35]
+	trusted := manager.Digest
+	update := manager.AddWithBatchUpdate(added)
+	if !acc.VerifyBatchUpdate(trusted, &update) {
+		t.Errorf("Batch update of the additions did not verify")
+	}
+	newDigest := update.NewDigest()
+	if !newDigest.IsEqual(&manager.Digest) {
+		t.Errorf("Batch update reports another digest")
+	}
+	w = MemWitnessBatchUpdate(w, y, &update)
+	if !acc.MemVerifySingle(manager.Digest, y, w) {
+		t.Errorf("Witness did not verify after the batch of additions")
+	}
+
+	// Consecutive powers of s, but over another digest
+	forged := BatchUpdate{Elements: added, Omega: acc.pkRange(0, uint64(len(added)+1))}
+	if acc.VerifyBatchUpdate(trusted, &forged) {
+		t.Errorf("Batch update over another digest verified")
+	}
+
+	deleted := append(append([]mcl.Fr{}, elements[2:9]...), elements[30:33]...)
+	trusted = manager.Digest
+	update = manager.DeleteWithBatchUpdate(deleted)
+	if !acc.VerifyBatchUpdate(trusted, &update) {
+		t.Errorf("Batch update of the deletions did not verify")
+	}
+	w = MemWitnessBatchUpdate(w, y, &update)
+	if !acc.MemVerifySingle(manager.Digest, y, w) {
+		t.Errorf("Witness did not verify after the batch of deletions")
+	}
+
+	// Same as the updates one element at a time
+	replay := NewDynAcc(&acc, elements[:20])
+	single = MemWitnessUpdate(single, y, replay.AddWithUpdates(added))
+	single = MemWitnessUpdate(single, y, replay.DeleteWithUpdates(deleted))
+	if !single.IsEqual(&w) {
+		t.Errorf("Batch update differs from the single element updates")
+	}
+
+	forged = BatchUpdate{Elements: deleted, Deleted: true, Omega: acc.pkRange(0, uint64(len(deleted)+1))}
+	if acc.VerifyBatchUpdate(trusted, &forged) {
+		t.Errorf("Batch deletion over another digest verified")
+	}
+	mcl.G1Dbl(&update.Omega[2], &update.Omega[2])
+	if acc.VerifyBatchUpdate(trusted, &update) {
+		t.Errorf("Tampered batch update verified")
+	}
+}
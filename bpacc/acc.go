@@ -1,25 +1,22 @@
 package bpacc
 
 import (
-	"math"
 	"path/filepath"
-	"sync"
 
 	"github.com/alinush/go-mcl"
 )
 
 const TRAPDOORNAME = "/trapdoors.data"
+const PUBLICNAME = "/public.data"
 const PRK_NAME = "/prk-%02d.data"
 const VRK_NAME = "/vrk-%02d.data"
 const VRK_KEA_NAME = "/vrk-kea-%02d.data"
 const PED_VRK_NAME = "/ped-vrk-%02d.data"
 const PED_VRK_KEA_NAME = "/ped-vrk-kea-%02d.data"
 
-const NFILES = 16
+const NFILES = 16 // Default number of segments, see Options
 const SPARE = 10
 
-var NCORES uint8
-
 type BpAcc struct {
 	seed string
 
@@ -54,6 +51,11 @@ type BpAcc struct {
 	// Extra generators
 	A []mcl.G1
 	B []mcl.G2
+
+	store   *mappedStore // Set by KeyGenLoadMapped, in place of the slices above.
+	pkStart uint64       // PK holds the powers [pkStart, pkStart+len(PK)). Only a BpVerifier sets it.
+
+	opts Options
 }
 
 type NonMemProof struct {
@@ -69,6 +71,7 @@ func (self *BpAcc) Setup(l uint64, seed string) {
 	self.Q = uint64(1) << self.ELL
 	self.seed = seed
 	self.S = SeedToFr(self.seed) // Use the seed to generate the trapdoor
+	check(SetMapToMode())
 	self.G, self.H = initG1G2()
 	mcl.G1Neg(&self.Gneg, &self.G)
 	mcl.G2Neg(&self.Hneg, &self.H)
@@ -90,17 +93,12 @@ func (self *BpAcc) Setup(l uint64, seed string) {
 }
 
 func (self *BpAcc) Init(L uint64, seed string, folderPath string) {
-	self.seed = seed
-	self.ELL = L
-	self.Q = uint64(1) << self.ELL
+	self.initShared(L, seed, folderPath)
 
 	// A degree q polynomial has q + 1 coefficients
 	self.PK = make([]mcl.G1, self.Q+1)
 	self.VK = make([]mcl.G2, self.Q+1)
 
-	// (NOTE): Currently our protocol needs only g^a. No higher powers of s needs to multiplied by Alpha.
-	self.PKAlpha = make([]mcl.G1, 1)
-
 	// Computes the KEA of VK
 	self.VKAlpha = make([]mcl.G2, self.Q+1)
 
@@ -109,6 +107,27 @@ func (self *BpAcc) Init(L uint64, seed string, folderPath string) {
 
 	// KEA of Pedersen vector commitment parameters
 	self.PedVKAlpha = make([]mcl.G2, self.Q+1)
+}
+
+// Everything Init sets, except the powers.
+func (self *BpAcc) initShared(L uint64, seed string, folderPath string) {
+	// The generators are hashed to the curve in this mode, see acc-generators.go
+	check(SetMapToMode())
+
+	self.seed = seed
+	self.ELL = L
+	self.Q = uint64(1) << self.ELL
+
+	self.PK = nil
+	self.VK = nil
+	self.VKAlpha = nil
+	self.PedVK = nil
+	self.PedVKAlpha = nil
+	self.pkStart = 0
+	self.Close()
+
+	// (NOTE): Currently our protocol needs only g^a. No higher powers of s needs to multiplied by Alpha.
+	self.PKAlpha = make([]mcl.G1, 1)
 
 	self.A = make([]mcl.G1, SPARE)
 	self.B = make([]mcl.G2, SPARE)
@@ -117,88 +136,197 @@ func (self *BpAcc) Init(L uint64, seed string, folderPath string) {
 }
 
 func (self *BpAcc) TrapdoorsGen() {
+	check(self.trapdoorsGen())
+}
+
+func (self *BpAcc) trapdoorsGen() error {
+	self.seedTrapdoors()
+	return self.saveTrapdoor()
+}
+
+func (self *BpAcc) seedTrapdoors() {
 	self.S = SeedToFr(self.seed)                 // Use the seed to generate the trapdoor
 	self.Alpha = SeedToFr(self.seed + "+ Alpha") // Generate the alpha for the KEA
 
+	self.GeneratorsGen()
+}
+
+// Same as TrapdoorsGen, but the trapdoors are sampled from the CSPRNG instead of the seed
+// and only the public part is written to the folder.
+func (self *BpAcc) TrapdoorsGenPublic() {
+	check(self.trapdoorsGenPublic())
+}
+
+func (self *BpAcc) trapdoorsGenPublic() error {
+	self.randomFr(&self.S)
+	self.randomFr(&self.Alpha)
+	for self.S.IsZero() || self.Alpha.IsZero() {
+		self.randomFr(&self.S)
+		self.randomFr(&self.Alpha)
+	}
+
+	self.GeneratorsGen()
+	return self.savePublic()
+}
+
+// Sets up the generators and everything else that is derived from them.
+// Expects S and Alpha to be already set.
+func (self *BpAcc) GeneratorsGen() {
 	self.G, self.H = initG1G2()
 	mcl.G1Neg(&self.Gneg, &self.G)
 	mcl.G2Neg(&self.Hneg, &self.H)
 	mcl.Pairing(&self.IdGT, &self.G, &self.H)
 	mcl.GTInv(&self.InvIdGT, &self.IdGT)
 
-	self.PedH.Random()
-	for i := range self.A {
-		self.A[i].Random()
-		self.B[i].Random()
-	}
+	self.PedH = HashToG2(GENERATORS_LABEL, "PedH")
+	self.A, self.B = DeriveSpareGenerators(GENERATORS_LABEL)
 
 	// Breaking the convention here to save this value along with trapdoors
 	mcl.G1Mul(&self.PKAlpha[0], &self.G, &self.Alpha)
-	self.SaveTrapdoor()
 }
 
 func (self *BpAcc) PrkVrkGen() {
-	var wg sync.WaitGroup
-
-	num := self.Q + 1 // Note that PK and VK has Q+1 terms
-	start := uint64(0)
-	step := uint64(math.Ceil(float64(num) / float64(NFILES)))
-	stop := step
-
-	for i := uint8(0); i < NFILES; i++ {
-		wg.Add(1)
-		go self.PrkVrkParallel(i, start, stop, &wg)
-
-		start += step
-		stop += step
-		stop = minUint64(stop, num)
+	check(self.prkVrkGen())
+}
 
-		if (i+1)%NCORES == 0 {
-			wg.Wait()
-		}
-	}
-	wg.Wait()
+// Computes all the powers with powersGen, then writes them to the segment files.
+func (self *BpAcc) prkVrkGen() error {
+	self.powersGen(0, self.Q+1) // Note that PK and VK has Q+1 terms
+	return self.saveSegments()
 }
 
 func (self *BpAcc) KeyGen(ncores uint8,
 	L uint64, seed string, folderPath string) {
-	NCORES = ncores
+	check(self.TryKeyGen(ncores, L, seed, folderPath))
+}
+
+// Same as KeyGen, but I/O failures are returned instead of panicking.
+// The errors wrap ErrMissingFile, ErrShortRead, ErrInvalidPoint, ErrEllMismatch or ErrFormat.
+func (self *BpAcc) TryKeyGen(ncores uint8,
+	L uint64, seed string, folderPath string) error {
+	self.setWorkers(ncores)
 	self.Init(L, seed, folderPath)
-	self.TrapdoorsGen()
-	self.PrkVrkGen()
+	if err := self.trapdoorsGen(); err != nil {
+		return err
+	}
+	if err := self.prkVrkGen(); err != nil {
+		return err
+	}
+	return self.saveManifest()
+}
+
+// Same parameters as KeyGen, but they are only computed in memory: nothing is read from or written to the disk.
+// Meant for tests and short-lived accumulators with a small L. The trapdoors are kept, like KeyGen.
+func (self *BpAcc) KeyGenMem(ncores uint8, L uint64, seed string) {
+	self.setWorkers(ncores)
+	self.Init(L, seed, "")
+	self.seedTrapdoors()
+	self.powersGen(0, self.Q+1)
 }
 
 func (self *BpAcc) KeyGenLoad(ncores uint8,
 	L uint64, seed string, folderPath string) {
-	NCORES = ncores
+	check(self.TryKeyGenLoad(ncores, L, seed, folderPath))
+}
+
+// Same as KeyGenLoad, but I/O failures are returned instead of panicking.
+func (self *BpAcc) TryKeyGenLoad(ncores uint8,
+	L uint64, seed string, folderPath string) error {
+	self.setWorkers(ncores)
 	self.Init(L, seed, folderPath)
-	self.LoadTrapdoor(L)
+	if err := self.verifyManifestPrefix(L, TRAPDOOR_COMPONENT); err != nil {
+		return err
+	}
+	if err := self.loadTrapdoor(L); err != nil {
+		return err
+	}
+	return self.loadSegments()
+}
+
+// Production key generation: nothing in folderPath allows forging proofs.
+// The trapdoors are sampled from the CSPRNG and wiped from memory once the powers are computed.
+// If secretPath is not empty, the manager trapdoor is stored there, separate from the public parameters.
+func (self *BpAcc) KeyGenPublic(ncores uint8,
+	L uint64, folderPath string, secretPath string) {
+	check(self.TryKeyGenPublic(ncores, L, folderPath, secretPath))
+}
+
+// Same as KeyGenPublic, but I/O failures are returned instead of panicking.
+func (self *BpAcc) TryKeyGenPublic(ncores uint8,
+	L uint64, folderPath string, secretPath string) error {
+	self.setWorkers(ncores)
+	self.Init(L, "", folderPath)
+	defer self.ClearTrapdoors()
+	if err := self.trapdoorsGenPublic(); err != nil {
+		return err
+	}
+	if err := self.prkVrkGen(); err != nil {
+		return err
+	}
+	if err := self.saveManifest(); err != nil {
+		return err
+	}
+	if secretPath != "" {
+		return self.saveManagerSecret(secretPath)
+	}
+	return nil
+}
+
+// Loads the parameters written by KeyGenPublic.
+// The resulting accumulator can prove and verify, but S and Alpha stay zero.
+func (self *BpAcc) KeyGenLoadPublic(ncores uint8,
+	L uint64, folderPath string) {
+	check(self.TryKeyGenLoadPublic(ncores, L, folderPath))
+}
+
+// Same as KeyGenLoadPublic, but I/O failures are returned instead of panicking.
+func (self *BpAcc) TryKeyGenLoadPublic(ncores uint8,
+	L uint64, folderPath string) error {
+	self.setWorkers(ncores)
+	self.Init(L, "", folderPath)
+	if err := self.verifyManifestPrefix(L, PUBLIC_COMPONENT); err != nil {
+		return err
+	}
+	if err := self.loadPublic(L); err != nil {
+		return err
+	}
+	return self.loadSegments()
+}
 
+// Loads all the segment files of PK, VK, VKAlpha, PedVK and PedVKAlpha.
+func (self *BpAcc) LoadSegments() {
+	check(self.loadSegments())
+}
+
+func (self *BpAcc) loadSegments() error {
 	var files []string
 	var err error
 
 	files, err = filepath.Glob(self.folderPath + "/prk-[0-9][0-9].data")
-	check(err)
-	self.G1Load(files, self.PK)
-	files = make([]string, 0)
-
-	files, err = filepath.Glob(self.folderPath + "/vrk-[0-9][0-9].data")
-	check(err)
-	self.G2Load(files, self.VK)
-	files = make([]string, 0)
-
-	files, err = filepath.Glob(self.folderPath + "/vrk-kea-[0-9][0-9].data")
-	check(err)
-	self.G2Load(files, self.VKAlpha)
-	files = make([]string, 0)
-
-	files, err = filepath.Glob(self.folderPath + "/ped-vrk-[0-9][0-9].data")
-	check(err)
-	self.G2Load(files, self.PedVK)
-	files = make([]string, 0)
-
-	files, err = filepath.Glob(self.folderPath + "/ped-vrk-kea-[0-9][0-9].data")
-	check(err)
-	self.G2Load(files, self.PedVKAlpha)
-	files = make([]string, 0)
+	if err != nil {
+		return err
+	}
+	if err = self.g1Load(files, self.PK); err != nil {
+		return err
+	}
+
+	components := []struct {
+		pattern string
+		varH    []mcl.G2
+	}{
+		{"/vrk-[0-9][0-9].data", self.VK},
+		{"/vrk-kea-[0-9][0-9].data", self.VKAlpha},
+		{"/ped-vrk-[0-9][0-9].data", self.PedVK},
+		{"/ped-vrk-kea-[0-9][0-9].data", self.PedVKAlpha},
+	}
+	for _, c := range components {
+		files, err = filepath.Glob(self.folderPath + c.pattern)
+		if err != nil {
+			return err
+		}
+		if err = self.g2Load(files, c.varH); err != nil {
+			return err
+		}
+	}
+	return nil
 }
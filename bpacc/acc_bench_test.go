@@ -17,7 +17,7 @@ func BenchmarkAccumulator(b *testing.B) {
 	ell := []uint64{8}
 
 	var acc BpAcc
-	acc.KeyGenLoad(8, ell[len(ell)-1], "xyz", "../pkvk-21")
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
 
 	var data []mcl.Fr
 	var digest_XI mcl.G1 // Digest of the entire accumulator
@@ -168,3 +168,61 @@ func BenchmarkAccumulator(b *testing.B) {
 		})
 	}
 }
+
+// Witnesses of every element of the set: MemProve against MemProveAll
+func BenchmarkMemProveAll(b *testing.B) {
+
+	ell := []uint64{6, 8, 10}
+
+	var acc BpAcc
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
+	elements := PopulateRandom(uint64(1) << ell[len(ell)-1])
+
+	for _, i := range ell {
+		set_size := uint64(1) << i
+		X := elements[:set_size]
+
+		b.Run(fmt.Sprintf("MemProve;2^%d", i), func(t *testing.B) {
+			t.ResetTimer()
+			for j := 0; j < t.N; j++ {
+				acc.MemProve(nil, X)
+			}
+		})
+
+		b.Run(fmt.Sprintf("MemProveAll;2^%d", i), func(t *testing.B) {
+			t.ResetTimer()
+			for j := 0; j < t.N; j++ {
+				acc.MemProveAll(X)
+			}
+		})
+	}
+}
+
+// Non-membership proofs of a batch: NonMemProve against NonMemProveAll
+func BenchmarkNonMemProveAll(b *testing.B) {
+
+	ell := []uint64{6, 8, 10}
+
+	var acc BpAcc
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
+	elements := PopulateRandom(uint64(1) << ell[len(ell)-1])
+
+	for _, i := range ell {
+		set_size := uint64(1) << i
+		X, I := elements[:set_size/2], elements[set_size/2:set_size]
+
+		b.Run(fmt.Sprintf("NonMemProve;2^%d", i-1), func(t *testing.B) {
+			t.ResetTimer()
+			for j := 0; j < t.N; j++ {
+				acc.NonMemProve(X, I)
+			}
+		})
+
+		b.Run(fmt.Sprintf("NonMemProveAll;2^%d", i-1), func(t *testing.B) {
+			t.ResetTimer()
+			for j := 0; j < t.N; j++ {
+				acc.NonMemProveAll(X, I)
+			}
+		})
+	}
+}
@@ -11,7 +11,7 @@ func TestMemProveVerify(t *testing.T) {
 
 	l := uint64(8)
 	var acc BpAcc
-	acc.Setup(l, "xyz")
+	acc.KeyGenMem(8, l, "xyz")
 	n := uint64(1 << l)
 	elements := PopulateRandom(n)
 	digest, _ := acc.Commit(elements)
@@ -33,7 +33,7 @@ func TestMemAggProveVerify(t *testing.T) {
 
 	l := uint64(8)
 	var acc BpAcc
-	acc.Setup(l, "xyz")
+	acc.KeyGenMem(8, l, "xyz")
 	n := uint64(1 << 4)
 	elements := PopulateRandom(n)
 
@@ -54,7 +54,7 @@ func TestNonMemProveVerify(t *testing.T) {
 
 	l := uint64(8)
 	var acc BpAcc
-	acc.Setup(l, "xyz")
+	acc.KeyGenMem(8, l, "xyz")
 	n := uint64(1 << 4)
 	elements := PopulateRandom(n)
 
@@ -77,7 +77,7 @@ func TestNonMemAggProveVerify(t *testing.T) {
 
 	l := uint64(8)
 	var acc BpAcc
-	acc.Setup(l, "xyz")
+	acc.KeyGenMem(8, l, "xyz")
 	n := uint64(1 << 4)
 	elements := PopulateRandom(n)
 
@@ -98,7 +98,7 @@ func TestPoEG2ProveVerify(t *testing.T) {
 
 	l := uint64(8)
 	var acc BpAcc
-	acc.Setup(l, "xyz")
+	acc.KeyGenMem(8, l, "xyz")
 	n := uint64(1 << 6)
 	elements := PopulateRandom(n)
 	v := fft.PolyTree(elements)
@@ -118,7 +118,7 @@ func TestPoEG1ProveVerify(t *testing.T) {
 
 	l := uint64(8)
 	var acc BpAcc
-	acc.Setup(l, "xyz")
+	acc.KeyGenMem(8, l, "xyz")
 	n := uint64(1 << 6)
 	elements := PopulateRandom(n)
 	v := fft.PolyTree(elements)
@@ -138,7 +138,7 @@ func TestNonMemBatchProveVerify(t *testing.T) {
 
 	l := uint64(8)
 	var acc BpAcc
-	acc.Setup(l, "xyz")
+	acc.KeyGenMem(8, l, "xyz")
 	n := uint64(1 << 4)
 	elements := PopulateRandom(n)
 
@@ -153,3 +153,98 @@ func TestNonMemBatchProveVerify(t *testing.T) {
 		t.Errorf("Batched non-membership proof verification failed.")
 	}
 }
+
+func TestKeyGenMem(t *testing.T) {
+
+	l := uint64(4)
+	var mem, disk BpAcc
+	mem.KeyGenMem(8, l, "xyz")
+	disk.KeyGen(8, l, "xyz", t.TempDir())
+
+	for i := uint64(0); i <= mem.Q; i++ {
+		if !mem.PK[i].IsEqual(&disk.PK[i]) || !mem.VK[i].IsEqual(&disk.VK[i]) || !mem.VKAlpha[i].IsEqual(&disk.VKAlpha[i]) ||
+			!mem.PedVK[i].IsEqual(&disk.PedVK[i]) || !mem.PedVKAlpha[i].IsEqual(&disk.PedVKAlpha[i]) {
+			t.Fatalf("Power %d differs from KeyGen", i)
+		}
+	}
+	if !mem.S.IsEqual(&disk.S) || !mem.PKAlpha[0].IsEqual(&disk.PKAlpha[0]) || !mem.B[0].IsEqual(&disk.B[0]) {
+		t.Errorf("Trapdoors or generators differ from KeyGen")
+	}
+}
+
+func TestBatchNonMemProveVerify(t *testing.T) {
+
+	l := uint64(6)
+	var acc BpAcc
+	acc.KeyGenMem(8, l, "xyz")
+	n := uint64(1 << 5)
+	elements := PopulateRandom(n)
+
+	// Unbalanced sets, thus the Bezout polynomials have different degrees
+	X, I := elements[:n-5], elements[n-5:]
+	digest, _ := acc.Commit(X)
+
+	A, B := acc.ProveBatchNonMem(X, I)
+	if !acc.VerifyBatchNonMem(digest, I, A, B) {
+		t.Errorf("Batched non-membership proof verification failed.")
+	}
+	if acc.VerifyBatchNonMem(digest, I[1:], A, B) {
+		t.Errorf("Batched non-membership proof verified for another set.")
+	}
+	if acc.VerifyBatchNonMem(digest, nil, A, B) {
+		t.Errorf("Batched non-membership proof verified for an empty set.")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Empty I did not panic.")
+			}
+		}()
+		acc.ProveBatchNonMem(X, nil)
+	}()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Overlapping sets did not panic.")
+		}
+	}()
+	acc.ProveBatchNonMem(X, elements[n-6:])
+}
+
+func TestBatchMemProveVerify(t *testing.T) {
+
+	l := uint64(6)
+	var acc BpAcc
+	acc.KeyGenMem(8, l, "xyz")
+	n := uint64(1 << 5)
+	elements := PopulateRandom(n + 1)
+
+	full, I := elements[:n], elements[3:10]
+	digest, _ := acc.Commit(full)
+
+	witness := acc.ProveBatchMem(full, I)
+	if !acc.VerifyBatchMem(digest, I, witness) {
+		t.Errorf("Batched membership proof verification failed.")
+	}
+	if acc.VerifyBatchMem(digest, I[1:], witness) {
+		t.Errorf("Batched membership proof verified for another set.")
+	}
+	if acc.VerifyBatchMem(digest, nil, digest) {
+		t.Errorf("Batched membership proof verified for an empty set.")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Empty I did not panic.")
+			}
+		}()
+		acc.ProveBatchMem(full, nil)
+	}()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Element outside of the set did not panic.")
+		}
+	}()
+	acc.ProveBatchMem(full, elements[n-1:])
+}
@@ -15,7 +15,7 @@ func TestPedersen(t *testing.T) {
 	elements := PopulateRandom(n)
 
 	var acc BpAcc
-	acc.KeyGenLoad(8, ell[len(ell)-1], "xyz", "../pkvk-21")
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
 
 	for _, i := range ell {
 		n = uint64(1) << i
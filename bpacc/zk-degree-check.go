@@ -4,7 +4,6 @@ import (
 	"encoding/binary"
 	"fmt"
 
-	"github.com/accumulators-agg/go-poly/fft"
 	"github.com/alinush/go-mcl"
 )
 
@@ -14,9 +13,9 @@ func (self *BpAcc) ZKDegCheckElementsProver(C_I PedG2, elements []mcl.Fr, transc
 		panic(fmt.Sprintf("Wants to commit %d, but the accumulator supports only %d", len(elements), self.Q))
 	}
 	// Build the polynomial from the factors
-	accPoly := fft.PolyTree(elements)
+	accPoly := polyTree(elements)
 
-	fmt.Println("After PolyTree", len(accPoly))
+	self.logln("After PolyTree", len(accPoly))
 
 	return self.ZKDegCheckProver(C_I, accPoly, transcript)
 }
@@ -31,11 +30,12 @@ func (self *BpAcc) ZKDegCheckProver(C_I PedG2, accPoly []mcl.Fr, transcript [32]
 	// Remove the highest degree coefficient
 	accPoly = accPoly[:len(accPoly)-1]
 
-	mcl.G2Sub(&proof.C_f, &C_I.Com, &self.VK[proof.D])
+	vkD := self.vkAt(proof.D)
+	mcl.G2Sub(&proof.C_f, &C_I.Com, &vkD)
 
 	// Fiat-Shamir
 	var c mcl.Fr
-	c.SetHashOf(proof.FiatShamir(transcript))
+	c.SetHashOf(proof.FiatShamir(transcript, self.opts.Encoding))
 
 	accPolyCopy := make([]mcl.Fr, len(accPoly))
 	for i := range accPoly {
@@ -49,8 +49,9 @@ func (self *BpAcc) ZKDegCheckProver(C_I PedG2, accPoly []mcl.Fr, transcript [32]
 	var rc mcl.Fr
 	mcl.FrMul(&rc, &C_I.R, &c)
 
-	C := self.PedersenG2(accPolyCopy, self.VK, rc, self.PedVK[self.Q-proof.D+1])
-	Ca := self.PedersenG2(accPolyCopy, self.VKAlpha, rc, self.PedVKAlpha[self.Q-proof.D+1])
+	n := uint64(len(accPolyCopy))
+	C := self.PedersenG2(accPolyCopy, self.vkRange(0, n), rc, self.pedVKAt(self.Q-proof.D+1))
+	Ca := self.PedersenG2(accPolyCopy, self.vkAlphaRange(0, n), rc, self.pedVKAlphaAt(self.Q-proof.D+1))
 
 	proof.C = C
 	proof.Ca = Ca
@@ -64,15 +65,17 @@ func (self *BpAcc) ZKDegCheckVerifier(C_I mcl.G2, proof ZKDegCheckProof, transcr
 	binary.LittleEndian.PutUint64(d_byte, proof.D)
 
 	var c mcl.Fr
-	c.SetHashOf(proof.FiatShamir(transcript))
+	c.SetHashOf(proof.FiatShamir(transcript, self.opts.Encoding))
 
 	var tempG1 mcl.G1
 	var tempG2 mcl.G2
 
-	mcl.G2Add(&tempG2, &proof.C_f, &self.VK[proof.D])
+	vkD := self.vkAt(proof.D)
+	mcl.G2Add(&tempG2, &proof.C_f, &vkD)
 	status = status && MultiPairing2(self.G, C_I, self.G, tempG2)
 
-	mcl.G1Mul(&tempG1, &self.PK[self.Q-proof.D+1], &c)
+	pk := self.pkAt(self.Q - proof.D + 1)
+	mcl.G1Mul(&tempG1, &pk, &c)
 	status = status && MultiPairing2(self.G, proof.C, tempG1, proof.C_f)
 
 	status = status && MultiPairing2(self.G, proof.Ca, self.PKAlpha[0], proof.C)
@@ -9,8 +9,8 @@ func (self *BpAcc) ZKMemProver(C_I PedG2, Pi_I mcl.G1, transcript [32]byte) zkMe
 	var proof zkMemProof
 	var tau_1, tau_2 mcl.Fr
 
-	tau_1.Random()
-	tau_2.Random()
+	self.randomFr(&tau_1)
+	self.randomFr(&tau_2)
 
 	var delta_1, delta_2 mcl.Fr
 	mcl.FrMul(&delta_1, &C_I.R, &tau_1)
@@ -24,11 +24,11 @@ func (self *BpAcc) ZKMemProver(C_I PedG2, Pi_I mcl.G1, transcript [32]byte) zkMe
 	mcl.G1Add(&proof.Pi_I_2, &aG1, &Pi_I)
 
 	var r_r, r_tau_1, r_tau_2, r_delta_1, r_delta_2 mcl.Fr
-	r_r.Random()
-	r_tau_1.Random()
-	r_tau_2.Random()
-	r_delta_1.Random()
-	r_delta_2.Random()
+	self.randomFr(&r_r)
+	self.randomFr(&r_tau_1)
+	self.randomFr(&r_tau_2)
+	self.randomFr(&r_delta_1)
+	self.randomFr(&r_delta_2)
 
 	var neg_r_delta_1, neg_r_delta_2 mcl.Fr
 	mcl.FrNeg(&neg_r_delta_1, &r_delta_1)
@@ -41,11 +41,11 @@ func (self *BpAcc) ZKMemProver(C_I PedG2, Pi_I mcl.G1, transcript [32]byte) zkMe
 	mcl.G1Mul(&bG1, &self.A[0], &neg_r_delta_1)
 	mcl.G1Mul(&cG1, &proof.Pi_I_2, &r_r)
 
-	mcl.MillerLoopVec(&proof.R_3, []mcl.G1{aG1, bG1, cG1}, []mcl.G2{C_I.Com, self.PedVK[0], self.PedVK[0]})
+	mcl.MillerLoopVec(&proof.R_3, []mcl.G1{aG1, bG1, cG1}, []mcl.G2{C_I.Com, self.PedH, self.PedH})
 	mcl.FinalExp(&proof.R_3, &proof.R_3)
 
 	var c mcl.Fr
-	c.SetHashOf(proof.FiatShamir(transcript))
+	c.SetHashOf(proof.FiatShamir(transcript, self.opts.Encoding))
 
 	mcl.FrMul(&proof.s_r, &c, &C_I.R)
 	mcl.FrMul(&proof.s_tau_1, &c, &tau_1)
@@ -68,7 +68,7 @@ func (self *BpAcc) ZKMemVerifier(proof zkMemProof, A_X mcl.G1, C_I mcl.G2, trans
 	status = true
 
 	var c, neg_c mcl.Fr
-	c.SetHashOf(proof.FiatShamir(transcript))
+	c.SetHashOf(proof.FiatShamir(transcript, self.opts.Encoding))
 	mcl.FrNeg(&neg_c, &c)
 
 	var R_1 mcl.G1
@@ -96,7 +96,7 @@ func (self *BpAcc) ZKMemVerifier(proof zkMemProof, A_X mcl.G1, C_I mcl.G2, trans
 	mcl.G1Mul(&A[2], &proof.Pi_I_2, &proof.s_r)
 	mcl.G1Mul(&A[3], &proof.Pi_I_2, &neg_c)
 	mcl.G1Mul(&A[4], &A_X, &c)
-	B = []mcl.G2{C_I, self.PedVK[0], self.PedVK[0], C_I, self.H}
+	B = []mcl.G2{C_I, self.PedH, self.PedH, C_I, self.H}
 
 	mcl.MillerLoopVec(&R_3, A, B)
 	mcl.FinalExp(&R_3, &R_3)
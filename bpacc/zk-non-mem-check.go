@@ -12,7 +12,7 @@ func (self *BpAcc) ZKNonMemProver(digest mcl.G1, C_I PedG2, A mcl.G2, B mcl.G1,
 	var tau []mcl.Fr
 	tau = make([]mcl.Fr, 4)
 	for i := range tau {
-		tau[i].Random()
+		self.randomFr(&tau[i])
 	}
 
 	var delta_3, delta_4 mcl.Fr
@@ -38,12 +38,12 @@ func (self *BpAcc) ZKNonMemProver(digest mcl.G1, C_I PedG2, A mcl.G2, B mcl.G1,
 	var r_delta_3, r_delta_4 mcl.Fr
 	r_tau = make([]mcl.Fr, 4)
 
-	r_r.Random()
+	self.randomFr(&r_r)
 	for i := range r_tau {
-		r_tau[i].Random()
+		self.randomFr(&r_tau[i])
 	}
-	r_delta_3.Random()
-	r_delta_4.Random()
+	self.randomFr(&r_delta_3)
+	self.randomFr(&r_delta_4)
 
 	mcl.G2MulVec(&proof.R_1, []mcl.G2{self.H, self.B[0]}, []mcl.Fr{r_tau[0], r_tau[1]})
 
@@ -60,13 +60,13 @@ func (self *BpAcc) ZKNonMemProver(digest mcl.G1, C_I PedG2, A mcl.G2, B mcl.G1,
 	mcl.G1Mul(&P[1], &self.A[0], &r_tau[2])
 	mcl.G1Mul(&P[2], &self.A[0], &neg_r_delta_3)
 	mcl.G1Mul(&P[3], &proof.B_bar[1], &r_r)
-	Q := []mcl.G2{self.B[0], C_I.Com, self.PedVK[0], self.PedVK[0]}
+	Q := []mcl.G2{self.B[0], C_I.Com, self.PedH, self.PedH}
 
 	mcl.MillerLoopVec(&proof.R_3, P, Q)
 	mcl.FinalExp(&proof.R_3, &proof.R_3)
 
 	var c mcl.Fr
-	c.SetHashOf(proof.FiatShamir(transcript))
+	c.SetHashOf(proof.FiatShamir(transcript, self.opts.Encoding))
 
 	mcl.FrMul(&proof.s_r, &c, &C_I.R)
 	mcl.FrMul(&proof.s_tau[0], &c, &tau[0])
@@ -94,7 +94,7 @@ func (self *BpAcc) ZKNonMemVerifier(proof zkNonMemProof, digest mcl.G1, C_I mcl.
 	status = true
 
 	var c, neg_c mcl.Fr
-	c.SetHashOf(proof.FiatShamir(transcript))
+	c.SetHashOf(proof.FiatShamir(transcript, self.opts.Encoding))
 	mcl.FrNeg(&neg_c, &c)
 
 	var R_1 mcl.G2
@@ -127,7 +127,7 @@ func (self *BpAcc) ZKNonMemVerifier(proof zkNonMemProof, digest mcl.G1, C_I mcl.
 	mcl.G1Mul(&P[5], &proof.B_bar[1], &neg_c)
 	mcl.G1Mul(&P[6], &self.G, &c)
 
-	Q := []mcl.G2{self.B[0], C_I, self.PedVK[0], self.PedVK[0], proof.A_bar[1], C_I, self.H}
+	Q := []mcl.G2{self.B[0], C_I, self.PedH, self.PedH, proof.A_bar[1], C_I, self.H}
 
 	mcl.MillerLoopVec(&R_3, P, Q)
 	mcl.FinalExp(&R_3, &R_3)
@@ -21,25 +21,25 @@ type zkMemProof struct {
 	s_delta_2 mcl.Fr
 }
 
-func (self *zkMemProof) FiatShamir(transcript [32]byte) []byte {
+func (self *zkMemProof) FiatShamir(transcript [32]byte, encoding PointEncoding) []byte {
 	data := make([]byte, 0)
 	data = append(data, transcript[:]...)
-	data = append(data, self.Pi_I_1.Serialize()...)
-	data = append(data, self.Pi_I_2.Serialize()...)
-	data = append(data, self.R_1.Serialize()...)
-	data = append(data, self.R_2.Serialize()...)
+	data = append(data, encoding.EncodeG1(&self.Pi_I_1)...)
+	data = append(data, encoding.EncodeG1(&self.Pi_I_2)...)
+	data = append(data, encoding.EncodeG1(&self.R_1)...)
+	data = append(data, encoding.EncodeG1(&self.R_2)...)
 	data = append(data, self.R_3.Serialize()...)
 	hash := blake2b.Sum256(data)
 	return hash[:]
 }
 
-func (self *zkMemProof) HashProof(transcript [32]byte) [32]byte {
+func (self *zkMemProof) HashProof(transcript [32]byte, encoding PointEncoding) [32]byte {
 	data := make([]byte, 0)
 	data = append(data, transcript[:]...)
-	data = append(data, self.Pi_I_1.Serialize()...)
-	data = append(data, self.Pi_I_2.Serialize()...)
-	data = append(data, self.R_1.Serialize()...)
-	data = append(data, self.R_2.Serialize()...)
+	data = append(data, encoding.EncodeG1(&self.Pi_I_1)...)
+	data = append(data, encoding.EncodeG1(&self.Pi_I_2)...)
+	data = append(data, encoding.EncodeG1(&self.R_1)...)
+	data = append(data, encoding.EncodeG1(&self.R_2)...)
 	data = append(data, self.R_3.Serialize()...)
 	data = append(data, self.s_r.Serialize()...)
 	data = append(data, self.s_tau_1.Serialize()...)
@@ -51,14 +51,14 @@ func (self *zkMemProof) HashProof(transcript [32]byte) [32]byte {
 	return hash
 }
 
-func (self *zkMemProof) ByteSize() uint64 {
+func (self *zkMemProof) ByteSize(encoding PointEncoding) uint64 {
 
 	var total uint64
 	total = 0
-	total += uint64(GetG1ByteSize()) // Pi_I_1
-	total += uint64(GetG1ByteSize()) // Pi_I_2
-	total += uint64(GetG1ByteSize()) // R_1
-	total += uint64(GetG1ByteSize()) // R_2
+	total += uint64(encoding.G1Size()) // Pi_I_1
+	total += uint64(encoding.G1Size()) // Pi_I_2
+	total += uint64(encoding.G1Size()) // R_1
+	total += uint64(encoding.G1Size()) // R_2
 
 	total += uint64(GetGTByteSize()) // R_3
 
@@ -92,33 +92,33 @@ func (self *zkNonMemProof) Setup() {
 	self.s_tau = make([]mcl.Fr, 4)
 }
 
-func (self *zkNonMemProof) FiatShamir(transcript [32]byte) []byte {
+func (self *zkNonMemProof) FiatShamir(transcript [32]byte, encoding PointEncoding) []byte {
 
 	data := make([]byte, 0)
 	data = append(data, transcript[:]...)
-	data = append(data, self.A_bar[0].Serialize()...)
-	data = append(data, self.A_bar[1].Serialize()...)
-	data = append(data, self.B_bar[0].Serialize()...)
-	data = append(data, self.B_bar[1].Serialize()...)
-	data = append(data, self.R_1.Serialize()...)
-	data = append(data, self.R_2[0].Serialize()...)
-	data = append(data, self.R_2[1].Serialize()...)
+	data = append(data, encoding.EncodeG2(&self.A_bar[0])...)
+	data = append(data, encoding.EncodeG2(&self.A_bar[1])...)
+	data = append(data, encoding.EncodeG1(&self.B_bar[0])...)
+	data = append(data, encoding.EncodeG1(&self.B_bar[1])...)
+	data = append(data, encoding.EncodeG2(&self.R_1)...)
+	data = append(data, encoding.EncodeG1(&self.R_2[0])...)
+	data = append(data, encoding.EncodeG1(&self.R_2[1])...)
 	data = append(data, self.R_3.Serialize()...)
 	hash := blake2b.Sum256(data)
 
 	return hash[:]
 }
 
-func (self *zkNonMemProof) HashProof(transcript [32]byte) [32]byte {
+func (self *zkNonMemProof) HashProof(transcript [32]byte, encoding PointEncoding) [32]byte {
 	data := make([]byte, 0)
 	data = append(data, transcript[:]...)
-	data = append(data, self.A_bar[0].Serialize()...)
-	data = append(data, self.A_bar[1].Serialize()...)
-	data = append(data, self.B_bar[0].Serialize()...)
-	data = append(data, self.B_bar[1].Serialize()...)
-	data = append(data, self.R_1.Serialize()...)
-	data = append(data, self.R_2[0].Serialize()...)
-	data = append(data, self.R_2[1].Serialize()...)
+	data = append(data, encoding.EncodeG2(&self.A_bar[0])...)
+	data = append(data, encoding.EncodeG2(&self.A_bar[1])...)
+	data = append(data, encoding.EncodeG1(&self.B_bar[0])...)
+	data = append(data, encoding.EncodeG1(&self.B_bar[1])...)
+	data = append(data, encoding.EncodeG2(&self.R_1)...)
+	data = append(data, encoding.EncodeG1(&self.R_2[0])...)
+	data = append(data, encoding.EncodeG1(&self.R_2[1])...)
 	data = append(data, self.R_3.Serialize()...)
 
 	data = append(data, self.s_r.Serialize()...)
@@ -133,16 +133,16 @@ func (self *zkNonMemProof) HashProof(transcript [32]byte) [32]byte {
 	return hash
 }
 
-func (self *zkNonMemProof) ByteSize() uint64 {
+func (self *zkNonMemProof) ByteSize(encoding PointEncoding) uint64 {
 
 	var total uint64
 	total = 0
 
-	total += uint64(GetG2ByteSize())                   // A_bar_2
-	total += uint64(len(self.B_bar) * GetG1ByteSize()) // B_bar
-	// total += uint64(GetG2ByteSize())                   // R_1
-	total += uint64(len(self.R_2) * GetG1ByteSize()) // R_2
-	total += uint64(GetGTByteSize())                 // R_3
+	total += uint64(encoding.G2Size())                   // A_bar_2
+	total += uint64(len(self.B_bar) * encoding.G1Size()) // B_bar
+	// total += uint64(encoding.G2Size())                   // R_1
+	total += uint64(len(self.R_2) * encoding.G1Size()) // R_2
+	total += uint64(GetGTByteSize())                   // R_3
 
 	total += uint64(GetFrByteSize()) // s_r
 	total += uint64(GetFrByteSize()) // s_tau_1
@@ -161,26 +161,26 @@ type ZKDegCheckProof struct {
 	Ca  mcl.G2
 }
 
-func (self *ZKDegCheckProof) FiatShamir(transcript [32]byte) []byte {
+func (self *ZKDegCheckProof) FiatShamir(transcript [32]byte, encoding PointEncoding) []byte {
 
 	d_byte := make([]byte, 8)
 	binary.LittleEndian.PutUint64(d_byte, self.D)
 	data := make([]byte, 0)
 	data = append(data, transcript[:]...)
 	data = append(data, d_byte...)
-	data = append(data, self.C_f.Serialize()...)
+	data = append(data, encoding.EncodeG2(&self.C_f)...)
 	hash := blake2b.Sum256(data)
 	return hash[:]
 }
 
-func (self *ZKDegCheckProof) ByteSize() uint64 {
+func (self *ZKDegCheckProof) ByteSize(encoding PointEncoding) uint64 {
 
 	var total uint64
 	total = 0
-	total += 8                       // D
-	total += uint64(GetG2ByteSize()) // C_f
-	total += uint64(GetG2ByteSize()) // C
-	total += uint64(GetG2ByteSize()) // Ca
+	total += 8                         // D
+	total += uint64(encoding.G2Size()) // C_f
+	total += uint64(encoding.G2Size()) // C
+	total += uint64(encoding.G2Size()) // Ca
 
 	return total
 }
@@ -190,12 +190,12 @@ type e2eMemProof struct {
 	zk_deg_proof ZKDegCheckProof
 }
 
-func (self *e2eMemProof) ByteSize() uint64 {
+func (self *e2eMemProof) ByteSize(encoding PointEncoding) uint64 {
 
 	var total uint64
 	total = 0
-	total += self.zk_mem_proof.ByteSize()
-	total += self.zk_deg_proof.ByteSize()
+	total += self.zk_mem_proof.ByteSize(encoding)
+	total += self.zk_deg_proof.ByteSize(encoding)
 
 	return total
 }
@@ -205,12 +205,12 @@ type e2eNonMemProof struct {
 	zk_deg_proof     ZKDegCheckProof
 }
 
-func (self *e2eNonMemProof) ByteSize() uint64 {
+func (self *e2eNonMemProof) ByteSize(encoding PointEncoding) uint64 {
 
 	var total uint64
 	total = 0
-	total += self.zk_non_mem_proof.ByteSize()
-	total += self.zk_deg_proof.ByteSize()
+	total += self.zk_non_mem_proof.ByteSize(encoding)
+	total += self.zk_deg_proof.ByteSize(encoding)
 
 	return total
 }
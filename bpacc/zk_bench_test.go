@@ -17,7 +17,7 @@ func BenchmarkZKAcc(b *testing.B) {
 	elements := PopulateRandom(n)
 
 	var acc BpAcc
-	acc.KeyGenLoad(8, ell[len(ell)-1], "xyz", "../pkvk-21")
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
 
 	var data []mcl.Fr
 	var digest_XI mcl.G1 // Digest of the entire accumulator
@@ -70,7 +70,7 @@ func BenchmarkZKAcc(b *testing.B) {
 
 				t.StartTimer()
 				zk_mem_proof.zk_mem_proof = acc.ZKMemProver(C_I, batchMemProof_I, transcript)
-				zk_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_mem_proof.zk_mem_proof.HashProof(transcript))
+				zk_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_mem_proof.zk_mem_proof.HashProof(transcript, acc.Options().Encoding))
 				//
 				t.StopTimer()
 				zk_mem_proofs = append(zk_mem_proofs, zk_mem_proof)
@@ -85,7 +85,7 @@ func BenchmarkZKAcc(b *testing.B) {
 				status = true
 				t.StartTimer()
 				status = status && acc.ZKMemVerifier(zk_mem_proof.zk_mem_proof, digest_XI, C_I.Com, transcript)
-				status = status && acc.ZKDegCheckVerifier(C_I.Com, zk_mem_proof.zk_deg_proof, zk_mem_proof.zk_mem_proof.HashProof(transcript))
+				status = status && acc.ZKDegCheckVerifier(C_I.Com, zk_mem_proof.zk_deg_proof, zk_mem_proof.zk_mem_proof.HashProof(transcript, acc.Options().Encoding))
 				t.StopTimer()
 				if status == false {
 					b.Errorf("Verifier-Mem failed")
@@ -100,7 +100,7 @@ func BenchmarkZKAcc(b *testing.B) {
 			for tn := 0; tn < t.N; tn++ {
 				t.StartTimer()
 				zk_non_mem_proof.zk_non_mem_proof = acc.ZKNonMemProver(digest_X, C_I, A, B, transcript)
-				zk_non_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_non_mem_proof.zk_non_mem_proof.HashProof(transcript))
+				zk_non_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_non_mem_proof.zk_non_mem_proof.HashProof(transcript, acc.Options().Encoding))
 				t.StopTimer()
 				zk_non_mem_proofs = append(zk_non_mem_proofs, zk_non_mem_proof)
 			}
@@ -115,7 +115,7 @@ func BenchmarkZKAcc(b *testing.B) {
 				status = true
 				t.StartTimer()
 				status = status && acc.ZKNonMemVerifier(zk_non_mem_proof.zk_non_mem_proof, digest_X, C_I.Com, transcript)
-				status = status && acc.ZKDegCheckVerifier(C_I.Com, zk_non_mem_proof.zk_deg_proof, zk_non_mem_proof.zk_non_mem_proof.HashProof(transcript))
+				status = status && acc.ZKDegCheckVerifier(C_I.Com, zk_non_mem_proof.zk_deg_proof, zk_non_mem_proof.zk_non_mem_proof.HashProof(transcript, acc.Options().Encoding))
 				t.StopTimer()
 				if status == false {
 					b.Errorf("Verifier-NonMem failed")
@@ -216,11 +216,11 @@ func BenchmarkZKAcc(b *testing.B) {
 		})
 	}
 
-	fmt.Println("SizeOf E2EMem:", zk_mem_proof.ByteSize(), "bytes =", fmt.Sprintf("%.2f", float64(zk_mem_proof.ByteSize())/1024.0), "KiB")
-	fmt.Println("SizeOf E2ENonMem:", zk_non_mem_proof.ByteSize(), "bytes =", fmt.Sprintf("%.2f", float64(zk_non_mem_proof.ByteSize())/1024.0), "KiB")
-	fmt.Println("SizeOf DegCheck:", zkDegCheckProof.ByteSize(), "bytes =", fmt.Sprintf("%.2f", float64(zkDegCheckProof.ByteSize())/1024.0), "KiB")
-	fmt.Println("SizeOf PureMem:", memProof.ByteSize(), "bytes =", fmt.Sprintf("%.2f", float64(memProof.ByteSize())/1024.0), "KiB")
-	fmt.Println("SizeOf PureNonMem:", nonMemProof.ByteSize(), "bytes =", fmt.Sprintf("%.2f", float64(nonMemProof.ByteSize())/1024.0), "KiB")
+	fmt.Println("SizeOf E2EMem:", zk_mem_proof.ByteSize(acc.Options().Encoding), "bytes =", fmt.Sprintf("%.2f", float64(zk_mem_proof.ByteSize(acc.Options().Encoding))/1024.0), "KiB")
+	fmt.Println("SizeOf E2ENonMem:", zk_non_mem_proof.ByteSize(acc.Options().Encoding), "bytes =", fmt.Sprintf("%.2f", float64(zk_non_mem_proof.ByteSize(acc.Options().Encoding))/1024.0), "KiB")
+	fmt.Println("SizeOf DegCheck:", zkDegCheckProof.ByteSize(acc.Options().Encoding), "bytes =", fmt.Sprintf("%.2f", float64(zkDegCheckProof.ByteSize(acc.Options().Encoding))/1024.0), "KiB")
+	fmt.Println("SizeOf PureMem:", memProof.ByteSize(acc.Options().Encoding), "bytes =", fmt.Sprintf("%.2f", float64(memProof.ByteSize(acc.Options().Encoding))/1024.0), "KiB")
+	fmt.Println("SizeOf PureNonMem:", nonMemProof.ByteSize(acc.Options().Encoding), "bytes =", fmt.Sprintf("%.2f", float64(nonMemProof.ByteSize(acc.Options().Encoding))/1024.0), "KiB")
 }
 
 func BenchmarkZKAccWitness(b *testing.B) {
@@ -228,7 +228,7 @@ func BenchmarkZKAccWitness(b *testing.B) {
 	ell := []uint64{5, 6, 7, 8, 9, 10, 11, 12}
 
 	var acc BpAcc
-	acc.KeyGenLoad(8, ell[len(ell)-1], "xyz", "../pkvk-21")
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
 
 	var data []mcl.Fr
 
@@ -304,7 +304,7 @@ func BenchmarkZKAccWitness(b *testing.B) {
 
 				// Generate zk batch proof
 				zk_mem_proof.zk_mem_proof = acc.ZKMemProver(C_I, batchMemProof_I, transcript)
-				zk_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_mem_proof.zk_mem_proof.HashProof(transcript))
+				zk_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_mem_proof.zk_mem_proof.HashProof(transcript, acc.Options().Encoding))
 				//
 				t.StopTimer()
 				zk_mem_proofs = append(zk_mem_proofs, zk_mem_proof)
@@ -338,7 +338,7 @@ func BenchmarkZKAccWitness(b *testing.B) {
 
 				// ZK batch prove
 				zk_non_mem_proof.zk_non_mem_proof = acc.ZKNonMemProver(batchMemProof_I, C_I, A, B, transcript)
-				zk_non_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_non_mem_proof.zk_non_mem_proof.HashProof(transcript))
+				zk_non_mem_proof.zk_deg_proof = acc.ZKDegCheckProver(C_I, I_x, zk_non_mem_proof.zk_non_mem_proof.HashProof(transcript, acc.Options().Encoding))
 				t.StopTimer()
 				zk_non_mem_proofs = append(zk_non_mem_proofs, zk_non_mem_proof)
 			}
@@ -16,7 +16,7 @@ func TestZKDegCheck(t *testing.T) {
 	elements := PopulateRandom(n)
 
 	var acc BpAcc
-	acc.KeyGenLoad(8, ell[len(ell)-1], "xyz", "../pkvk-21")
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
 
 	var data []mcl.Fr
 	var digest_XI mcl.G1 // Digest of the entire accumulator
@@ -68,7 +68,7 @@ func TestZKAccMem(t *testing.T) {
 	elements := PopulateRandom(n)
 
 	var acc BpAcc
-	acc.KeyGenLoad(8, ell[len(ell)-1], "xyz", "../pkvk-21")
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
 
 	var data []mcl.Fr
 	var digest_XI mcl.G1 // Digest of the entire accumulator
@@ -87,7 +87,10 @@ func TestZKAccMem(t *testing.T) {
 		digest_X, _ = acc.CommitFakeG1(X) // This is also the batch proof of set I
 		fmt.Println("Done with commit (2/2).")
 
-		batchProof_I := digest_X
+		batchProof_I := acc.ProveBatchMem(data, I)
+		if !batchProof_I.IsEqual(&digest_X) || !acc.VerifyBatchMem(digest_XI, I, batchProof_I) {
+			t.Errorf("Batch membership witness is wrong %d", i)
+		}
 		fmt.Println("Generated Membership.")
 
 		t.Run(fmt.Sprintf("MemCheck"), func(t *testing.T) {
@@ -117,7 +120,7 @@ func TestZKAccNonMem(t *testing.T) {
 	elements := PopulateRandom(n)
 
 	var acc BpAcc
-	acc.KeyGenLoad(8, ell[len(ell)-1], "xyz", "../pkvk-21")
+	acc.KeyGenMem(8, ell[len(ell)-1], "xyz")
 
 	var data []mcl.Fr
 	var digest_X mcl.G1 // Digest of the partial accumulator
@@ -144,7 +147,7 @@ func TestZKAccNonMem(t *testing.T) {
 			accPoly := fft.PolyTree(I)
 			C_I := PedG2{acc.PedersenG2(accPoly, acc.VK, random, acc.PedVK[0]), random}
 
-			A, B := acc.ProveBatchNonMemFake(X, I)
+			A, B := acc.ProveBatchNonMem(X, I)
 
 			var proof zkNonMemProof
 			proof = acc.ZKNonMemProver(digest_X, C_I, A, B, transcript)